@@ -0,0 +1,46 @@
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterSyncWindowSpec defines the desired state of ClusterSyncWindow
+type ClusterSyncWindowSpec struct {
+	// TenantSelector selects the tenants by their labels.
+	// An empty selector selects all tenants.
+	// +optional
+	TenantSelector *metav1.LabelSelector `json:"tenantSelector,omitempty"`
+
+	// Tenants is a list of the names of the tenants.
+	// The sync windows apply to the tenants listed here in addition to the tenants selected by `tenantSelector`.
+	// +optional
+	Tenants []string `json:"tenants,omitempty"`
+
+	// SyncWindows is a list of sync windows
+	// +kubebuilder:validation:Required
+	SyncWindows SyncWindows `json:"syncWindows"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// ClusterSyncWindow is the Schema for the clustersyncwindows API
+type ClusterSyncWindow struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ClusterSyncWindowSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterSyncWindowList contains a list of ClusterSyncWindow
+type ClusterSyncWindowList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterSyncWindow `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterSyncWindow{}, &ClusterSyncWindowList{})
+}
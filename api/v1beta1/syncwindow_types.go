@@ -49,15 +49,61 @@ type SyncWindowStatus struct {
 	// Conditions is an array of conditions.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ObservedGeneration is the generation of this resource that the status is based upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// AppProject is the reference to the AppProject that the sync windows are merged into.
+	// +optional
+	AppProject *ObjectReference `json:"appProject,omitempty"`
+
+	// LastSyncTime is the last time the sync windows were reflected to the AppProject successfully.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// Windows are the states of the sync windows in the same order as `spec.syncWindows`.
+	// +optional
+	Windows []SyncWindowState `json:"windows,omitempty"`
 }
 
+// SyncWindowState represents whether a sync window is active.
+type SyncWindowState struct {
+	// Kind is the kind of the sync window.
+	Kind string `json:"kind"`
+
+	// Active is true if the sync window is active now.
+	Active bool `json:"active"`
+
+	// NextTransitionTime is the time when the sync window opens or closes next.
+	// +optional
+	NextTransitionTime *metav1.Time `json:"nextTransitionTime,omitempty"`
+}
+
+const (
+	ConditionSynced   string = "Synced"
+	ConditionNarrowed string = "Narrowed"
+)
+
+// Reasons of the Synced condition.
+// When the AppProject fails to be reconciled, the reasons of the conditions of the Tenant are also used.
+const (
+	ReasonSynced  string = "OK"
+	ReasonInvalid string = "Invalid"
+)
+
+// Reasons of the Narrowed condition.
 const (
-	ConditionSynced string = "Synced"
+	ReasonNotNarrowed     string = "NotNarrowed"
+	ReasonEntriesNarrowed string = "EntriesNarrowed"
+	ReasonEntriesDropped  string = "EntriesDropped"
 )
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type==\"Synced\")].status"
+//+kubebuilder:printcolumn:name="Last Sync",type="date",JSONPath=".status.lastSyncTime"
+//+kubebuilder:printcolumn:name="Active",type="string",JSONPath=".status.windows[?(@.active==true)].kind"
 
 // SyncWindow is the Schema for the syncwindows API
 type SyncWindow struct {
@@ -19,15 +19,38 @@ type TenantSpec struct {
 	// +optional
 	Delegates []DelegateSpec `json:"delegates,omitempty"`
 
+	// Parent is the name of the parent tenant.
+	// This tenant inherits repositories, extra parameters and delegates from its ancestors,
+	// and the ancestors are delegated the admin role on this tenant.
+	// +optional
+	Parent string `json:"parent,omitempty"`
+
+	// NetworkPeers is a list of other tenants that are allowed to access namespaces of this tenant
+	// when the network isolation is enabled in the configuration.
+	// Tenants in `delegates` are allowed implicitly.
+	// +optional
+	NetworkPeers []string `json:"networkPeers,omitempty"`
+
 	// ControllerName is the name of the application-controller that manages this tenant's applications.
-	// If not specified, the default controller is used.
+	// If not specified, the controller is chosen by the sharding policy in the configuration.
 	// +optional
 	ControllerName string `json:"controllerName,omitempty"`
 
 	// ExtraParams is a map of extra parameters that can be used in the templates.
+	// They must conform to `extraParams.schema` in the configuration if it is specified.
 	// +kubebuilder:pruning:PreserveUnknownFields
 	// +optional
 	ExtraParams *Params `json:"extraParams,omitempty"`
+
+	// ResourceQuotaTemplate is a template for ResourceQuota resource that is created on root namespaces of this tenant.
+	// This supersedes `namespace.resourceQuotaTemplate` in the configuration.
+	// +optional
+	ResourceQuotaTemplate string `json:"resourceQuotaTemplate,omitempty"`
+
+	// LimitRangeTemplate is a template for LimitRange resource that is created on root namespaces of this tenant.
+	// This supersedes `namespace.limitRangeTemplate` in the configuration.
+	// +optional
+	LimitRangeTemplate string `json:"limitRangeTemplate,omitempty"`
 }
 
 // RootNamespaceSpec defines the desired state of Namespace.
@@ -50,10 +73,167 @@ type RootNamespaceSpec struct {
 // ArgoCDSpec defines the desired state of the settings for Argo CD.
 type ArgoCDSpec struct {
 	// Repositories contains list of repository URLs which can be used by the tenant.
+	// The repositories must be allowed in the configuration.
 	// +optional
 	Repositories []string `json:"repositories,omitempty"`
+
+	// RepositoryCredentials is a list of credentials for the repositories of this tenant.
+	// Argo CD repository credentials scoped to the AppProject are generated from them if it is enabled in the configuration.
+	// +optional
+	RepositoryCredentials []RepositoryCredentialSpec `json:"repositoryCredentials,omitempty"`
+
+	// SyncWindowScope is the scope of sync windows in SyncWindow resources of this tenant.
+	// `Tenant` reflects the sync windows to the AppProject as they are.
+	// `Namespace` narrows the sync windows to the applications in the namespace where the SyncWindow resource is created.
+	// If not specified, `Tenant` is used.
+	// +kubebuilder:validation:Enum=Tenant;Namespace
+	// +optional
+	SyncWindowScope SyncWindowScope `json:"syncWindowScope,omitempty"`
+
+	// NamespacePatterns is a list of glob patterns that match the namespaces of this tenant, such as `app-a-*`.
+	// If compaction of namespaces is enabled in the configuration, the namespaces matching the patterns are replaced with the patterns
+	// in the AppProject and the ConfigMaps for the application-controllers.
+	// The patterns must not match namespaces that do not belong to this tenant.
+	// +optional
+	NamespacePatterns []string `json:"namespacePatterns,omitempty"`
+
+	// Destinations is a list of clusters where the applications of this tenant can be deployed.
+	// The clusters must be allowed in the configuration.
+	// +optional
+	Destinations []DestinationSpec `json:"destinations,omitempty"`
+
+	// Roles is a list of project roles that are merged into the AppProject of this tenant.
+	// Roles with the same names as those rendered from the template are ignored.
+	// +optional
+	Roles []ProjectRoleSpec `json:"roles,omitempty"`
+
+	// ResourcePolicy is the policy of the resources that the applications of this tenant can deploy.
+	// +optional
+	ResourcePolicy *ResourcePolicySpec `json:"resourcePolicy,omitempty"`
+}
+
+// ResourcePolicySpec defines the resources that the applications of a tenant can deploy.
+// The lists are merged into those of the AppProject rendered from the template and the resource policy in the configuration.
+// The whitelists can only narrow the resources allowed by the template and the configuration.
+type ResourcePolicySpec struct {
+	// Profile is the name of a resource policy in the configuration.
+	// If not specified, the default resource policy in the configuration is used.
+	// +optional
+	Profile string `json:"profile,omitempty"`
+
+	// ClusterResourceWhitelist is a list of cluster-scoped resources that are allowed.
+	// The resources not allowed by the template or the configuration are ignored.
+	// +optional
+	ClusterResourceWhitelist []metav1.GroupKind `json:"clusterResourceWhitelist,omitempty"`
+
+	// ClusterResourceBlacklist is a list of cluster-scoped resources that are denied.
+	// +optional
+	ClusterResourceBlacklist []metav1.GroupKind `json:"clusterResourceBlacklist,omitempty"`
+
+	// NamespaceResourceWhitelist is a list of namespaced resources that are allowed.
+	// The resources not allowed by the template or the configuration are ignored.
+	// +optional
+	NamespaceResourceWhitelist []metav1.GroupKind `json:"namespaceResourceWhitelist,omitempty"`
+
+	// NamespaceResourceBlacklist is a list of namespaced resources that are denied.
+	// +optional
+	NamespaceResourceBlacklist []metav1.GroupKind `json:"namespaceResourceBlacklist,omitempty"`
+}
+
+// ProjectRoleSpec defines a role of the AppProject of a tenant.
+type ProjectRoleSpec struct {
+	// Name is the name of the role.
+	// Names starting with `delegate-` are reserved for the read-only roles of delegated tenants.
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9]([-_a-zA-Z0-9]*[a-zA-Z0-9])?$`
+	// +kubebuilder:validation:Required
+	Name string `json:"name"`
+
+	// Description is the description of the role.
+	// +optional
+	Description string `json:"description,omitempty"`
+
+	// Policies is a list of policies of the role.
+	// +optional
+	Policies []ProjectPolicySpec `json:"policies,omitempty"`
+
+	// Groups is a list of groups of SSO users that have the role.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
 }
 
+// ProjectPolicySpec defines a policy of a role of an AppProject.
+// A policy is rendered for each combination of the resources, the verbs and the applications.
+type ProjectPolicySpec struct {
+	// Resources is a list of resources of Argo CD.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Enum=applications;applicationsets;logs;exec
+	Resources []string `json:"resources"`
+
+	// Verbs is a list of actions on the resources, such as `get`, `sync` and `action/*`.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Pattern=`^[^,\s]+$`
+	Verbs []string `json:"verbs"`
+
+	// Applications is a list of glob patterns of the applications in the AppProject.
+	// If not specified, all applications are matched.
+	// +kubebuilder:validation:items:Pattern=`^[^,\s]+$`
+	// +optional
+	Applications []string `json:"applications,omitempty"`
+
+	// Effect is the effect of the policy.
+	// If not specified, `allow` is used.
+	// +kubebuilder:validation:Enum=allow;deny
+	// +optional
+	Effect PolicyEffect `json:"effect,omitempty"`
+}
+
+// PolicyEffect is the effect of a policy of an AppProject.
+type PolicyEffect string
+
+const (
+	PolicyEffectAllow PolicyEffect = "allow"
+	PolicyEffectDeny  PolicyEffect = "deny"
+)
+
+// DestinationSpec defines a cluster where the applications of a tenant can be deployed.
+type DestinationSpec struct {
+	// Name is the name of the cluster in Argo CD.
+	// Either name or server must be specified.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Server is the URL of the API server of the cluster.
+	// Either name or server must be specified.
+	// +optional
+	Server string `json:"server,omitempty"`
+
+	// Namespaces is a list of glob patterns of the namespaces in the cluster.
+	// If not specified, the namespaces of this tenant are used.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+}
+
+// RepositoryCredentialSpec defines a credential for a repository of a tenant.
+type RepositoryCredentialSpec struct {
+	// URL is the URL of the repository.
+	// The repository must be allowed in the configuration.
+	// +kubebuilder:validation:Required
+	URL string `json:"url"`
+
+	// SecretRef is the reference to the Secret that contains the credential.
+	// The Secret must be in a namespace of this tenant.
+	// +kubebuilder:validation:Required
+	SecretRef ObjectReference `json:"secretRef"`
+}
+
+// SyncWindowScope is the scope of sync windows in SyncWindow resources.
+type SyncWindowScope string
+
+const (
+	SyncWindowScopeTenant    SyncWindowScope = "Tenant"
+	SyncWindowScopeNamespace SyncWindowScope = "Namespace"
+)
+
 // DelegateSpec defines a tenant that is delegated access to a tenant.
 type DelegateSpec struct {
 	// Name is the name of a delegated tenant.
@@ -63,6 +243,11 @@ type DelegateSpec struct {
 	// Roles is a list of roles that the tenant has.
 	// +kubebuilder:validation:MinItems=1
 	Roles []string `json:"roles"`
+
+	// Groups is a list of groups that are given the read-only role named `delegate-<name>` on the AppProject of this tenant.
+	// If not specified, the groups are rendered from `argocd.delegateGroups` in the configuration.
+	// +optional
+	Groups []string `json:"groups,omitempty"`
 }
 
 // TenantHealth defines the observed state of Tenant.
@@ -83,16 +268,99 @@ type TenantStatus struct {
 	// Conditions is an array of conditions.
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// RootNamespaces are the names of root namespaces that belong to this tenant.
+	// +optional
+	RootNamespaces []string `json:"rootNamespaces,omitempty"`
+
+	// Namespaces are the names of all namespaces that belong to this tenant, including sub-namespaces.
+	// +optional
+	Namespaces []string `json:"namespaces,omitempty"`
+
+	// DelegatedNamespaces are the names of namespaces that belong to the delegated tenants.
+	// +optional
+	DelegatedNamespaces []string `json:"delegatedNamespaces,omitempty"`
+
+	// AppProject is the reference to the AppProject of this tenant.
+	// +optional
+	AppProject *ObjectReference `json:"appProject,omitempty"`
+
+	// ControllerName is the name of the application-controller that this tenant is assigned to.
+	// This is `controllerName` in the spec if specified, otherwise it is chosen by the sharding policy in the configuration.
+	// +optional
+	ControllerName string `json:"controllerName,omitempty"`
+
+	// ConfigMap is the reference to the ConfigMap for the application-controller that manages this tenant's applications.
+	// +optional
+	ConfigMap *ObjectReference `json:"configMap,omitempty"`
+
+	// Migration is the migration of this tenant between application-controllers in progress.
+	// +optional
+	Migration *ControllerMigration `json:"migration,omitempty"`
 }
 
+// ControllerMigrationPhase is the phase of a migration between application-controllers.
+// +kubebuilder:validation:Enum=Draining
+type ControllerMigrationPhase string
+
+const (
+	// ControllerMigrationDraining means that the namespaces of the tenant have been removed from the ConfigMap of the old controller
+	// and are waiting to be added to the ConfigMap of the new controller.
+	ControllerMigrationDraining = ControllerMigrationPhase("Draining")
+)
+
+// ControllerMigration represents a migration of a tenant between application-controllers.
+type ControllerMigration struct {
+	// Phase is the phase of the migration.
+	Phase ControllerMigrationPhase `json:"phase"`
+
+	// From is the name of the application-controller that managed this tenant's applications.
+	From string `json:"from"`
+
+	// To is the name of the application-controller that will manage this tenant's applications.
+	To string `json:"to"`
+
+	// StartTime is the time when the migration started.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// ObjectReference is a reference to a namespaced object.
+type ObjectReference struct {
+	// Namespace is the namespace of the object.
+	Namespace string `json:"namespace"`
+
+	// Name is the name of the object.
+	Name string `json:"name"`
+}
+
+const (
+	ConditionReady            string = "Ready"
+	ConditionNamespacesReady  string = "NamespacesReady"
+	ConditionAppProjectReady  string = "AppProjectReady"
+	ConditionConfigMapReady   string = "ConfigMapReady"
+	ConditionSyncWindowsReady string = "SyncWindowsReady"
+)
+
+// Reasons of the conditions except for Ready.
 const (
-	ConditionReady string = "Ready"
+	ReasonReconciled             string = "Reconciled"
+	ReasonDelegateNotFound       string = "DelegateNotFound"
+	ReasonParentNotFound         string = "ParentNotFound"
+	ReasonCyclicHierarchy        string = "CyclicHierarchy"
+	ReasonInvalidTemplate        string = "InvalidTemplate"
+	ReasonApplyFailed            string = "ApplyFailed"
+	ReasonListFailed             string = "ListFailed"
+	ReasonStatusUpdateFailed     string = "StatusUpdateFailed"
+	ReasonSecretNotFound         string = "SecretNotFound"
+	ReasonResourcePolicyNotFound string = "ResourcePolicyNotFound"
+	ReasonInvalidExtraParams     string = "InvalidExtraParams"
 )
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
 //+kubebuilder:printcolumn:name="STATUS",type="string",JSONPath=".status.health"
+//+kubebuilder:printcolumn:name="CONTROLLER",type="string",JSONPath=".status.controllerName",priority=1
 
 // Tenant is the Schema for the tenants API.
 type Tenant struct {
@@ -17,6 +17,35 @@ func (in *ArgoCDSpec) DeepCopyInto(out *ArgoCDSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.RepositoryCredentials != nil {
+		in, out := &in.RepositoryCredentials, &out.RepositoryCredentials
+		*out = make([]RepositoryCredentialSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespacePatterns != nil {
+		in, out := &in.NamespacePatterns, &out.NamespacePatterns
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Destinations != nil {
+		in, out := &in.Destinations, &out.Destinations
+		*out = make([]DestinationSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]ProjectRoleSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResourcePolicy != nil {
+		in, out := &in.ResourcePolicy, &out.ResourcePolicy
+		*out = new(ResourcePolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ArgoCDSpec.
@@ -29,6 +58,116 @@ func (in *ArgoCDSpec) DeepCopy() *ArgoCDSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSyncWindow) DeepCopyInto(out *ClusterSyncWindow) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSyncWindow.
+func (in *ClusterSyncWindow) DeepCopy() *ClusterSyncWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSyncWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterSyncWindow) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSyncWindowList) DeepCopyInto(out *ClusterSyncWindowList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterSyncWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSyncWindowList.
+func (in *ClusterSyncWindowList) DeepCopy() *ClusterSyncWindowList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSyncWindowList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterSyncWindowList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterSyncWindowSpec) DeepCopyInto(out *ClusterSyncWindowSpec) {
+	*out = *in
+	if in.TenantSelector != nil {
+		in, out := &in.TenantSelector, &out.TenantSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenants != nil {
+		in, out := &in.Tenants, &out.Tenants
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SyncWindows != nil {
+		in, out := &in.SyncWindows, &out.SyncWindows
+		*out = make(SyncWindows, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(SyncWindowSetting)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterSyncWindowSpec.
+func (in *ClusterSyncWindowSpec) DeepCopy() *ClusterSyncWindowSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterSyncWindowSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerMigration) DeepCopyInto(out *ControllerMigration) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerMigration.
+func (in *ControllerMigration) DeepCopy() *ControllerMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DelegateSpec) DeepCopyInto(out *DelegateSpec) {
 	*out = *in
@@ -37,6 +176,11 @@ func (in *DelegateSpec) DeepCopyInto(out *DelegateSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DelegateSpec.
@@ -49,6 +193,41 @@ func (in *DelegateSpec) DeepCopy() *DelegateSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DestinationSpec) DeepCopyInto(out *DestinationSpec) {
+	*out = *in
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DestinationSpec.
+func (in *DestinationSpec) DeepCopy() *DestinationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DestinationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectReference) DeepCopyInto(out *ObjectReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectReference.
+func (in *ObjectReference) DeepCopy() *ObjectReference {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Params.
 func (in *Params) DeepCopy() *Params {
 	if in == nil {
@@ -59,6 +238,114 @@ func (in *Params) DeepCopy() *Params {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectPolicySpec) DeepCopyInto(out *ProjectPolicySpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Verbs != nil {
+		in, out := &in.Verbs, &out.Verbs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Applications != nil {
+		in, out := &in.Applications, &out.Applications
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectPolicySpec.
+func (in *ProjectPolicySpec) DeepCopy() *ProjectPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProjectRoleSpec) DeepCopyInto(out *ProjectRoleSpec) {
+	*out = *in
+	if in.Policies != nil {
+		in, out := &in.Policies, &out.Policies
+		*out = make([]ProjectPolicySpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Groups != nil {
+		in, out := &in.Groups, &out.Groups
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProjectRoleSpec.
+func (in *ProjectRoleSpec) DeepCopy() *ProjectRoleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ProjectRoleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryCredentialSpec) DeepCopyInto(out *RepositoryCredentialSpec) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryCredentialSpec.
+func (in *RepositoryCredentialSpec) DeepCopy() *RepositoryCredentialSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryCredentialSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourcePolicySpec) DeepCopyInto(out *ResourcePolicySpec) {
+	*out = *in
+	if in.ClusterResourceWhitelist != nil {
+		in, out := &in.ClusterResourceWhitelist, &out.ClusterResourceWhitelist
+		*out = make([]v1.GroupKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.ClusterResourceBlacklist != nil {
+		in, out := &in.ClusterResourceBlacklist, &out.ClusterResourceBlacklist
+		*out = make([]v1.GroupKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceResourceWhitelist != nil {
+		in, out := &in.NamespaceResourceWhitelist, &out.NamespaceResourceWhitelist
+		*out = make([]v1.GroupKind, len(*in))
+		copy(*out, *in)
+	}
+	if in.NamespaceResourceBlacklist != nil {
+		in, out := &in.NamespaceResourceBlacklist, &out.NamespaceResourceBlacklist
+		*out = make([]v1.GroupKind, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourcePolicySpec.
+func (in *ResourcePolicySpec) DeepCopy() *ResourcePolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourcePolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RootNamespaceSpec) DeepCopyInto(out *RootNamespaceSpec) {
 	*out = *in
@@ -203,6 +490,25 @@ func (in *SyncWindowSpec) DeepCopy() *SyncWindowSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SyncWindowState) DeepCopyInto(out *SyncWindowState) {
+	*out = *in
+	if in.NextTransitionTime != nil {
+		in, out := &in.NextTransitionTime, &out.NextTransitionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncWindowState.
+func (in *SyncWindowState) DeepCopy() *SyncWindowState {
+	if in == nil {
+		return nil
+	}
+	out := new(SyncWindowState)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *SyncWindowStatus) DeepCopyInto(out *SyncWindowStatus) {
 	*out = *in
@@ -213,6 +519,22 @@ func (in *SyncWindowStatus) DeepCopyInto(out *SyncWindowStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AppProject != nil {
+		in, out := &in.AppProject, &out.AppProject
+		*out = new(ObjectReference)
+		**out = **in
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Windows != nil {
+		in, out := &in.Windows, &out.Windows
+		*out = make([]SyncWindowState, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SyncWindowStatus.
@@ -327,6 +649,11 @@ func (in *TenantSpec) DeepCopyInto(out *TenantSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NetworkPeers != nil {
+		in, out := &in.NetworkPeers, &out.NetworkPeers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	if in.ExtraParams != nil {
 		in, out := &in.ExtraParams, &out.ExtraParams
 		*out = (*in).DeepCopy()
@@ -353,6 +680,36 @@ func (in *TenantStatus) DeepCopyInto(out *TenantStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.RootNamespaces != nil {
+		in, out := &in.RootNamespaces, &out.RootNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Namespaces != nil {
+		in, out := &in.Namespaces, &out.Namespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DelegatedNamespaces != nil {
+		in, out := &in.DelegatedNamespaces, &out.DelegatedNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AppProject != nil {
+		in, out := &in.AppProject, &out.AppProject
+		*out = new(ObjectReference)
+		**out = **in
+	}
+	if in.ConfigMap != nil {
+		in, out := &in.ConfigMap, &out.ConfigMap
+		*out = new(ObjectReference)
+		**out = **in
+	}
+	if in.Migration != nil {
+		in, out := &in.Migration, &out.Migration
+		*out = new(ControllerMigration)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TenantStatus.
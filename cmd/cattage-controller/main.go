@@ -1,6 +1,9 @@
 package main
 
 import (
+	// The image has no time zone database, which is needed to evaluate the time zones of sync windows.
+	_ "time/tzdata"
+
 	"github.com/cybozu-go/cattage/cmd/cattage-controller/sub"
 )
 
@@ -0,0 +1,191 @@
+package sub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/controller"
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/yaml"
+)
+
+var renderOptions struct {
+	configFile string
+	files      []string
+	diff       bool
+}
+
+var renderCmd = &cobra.Command{
+	Use:   "render [TENANT...]",
+	Short: "render resources for tenants without applying them",
+	Long: `Render resources for tenants in the same way as the controller, without applying them.
+
+Tenants are read from the cluster by name, or from files specified with --filename.
+If neither is specified, all tenants in the cluster are rendered.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		return renderMain(cmd.Context(), cmd.OutOrStdout(), args)
+	},
+}
+
+func renderMain(ctx context.Context, w io.Writer, names []string) error {
+	ctrl.SetLogger(zap.New(zap.WriteTo(os.Stderr)))
+
+	scheme, err := newScheme()
+	if err != nil {
+		return err
+	}
+	cfg, err := loadConfig(renderOptions.configFile)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configurations: %w", err)
+	}
+
+	c, err := cluster.New(ctrl.GetConfigOrDie(), func(o *cluster.Options) {
+		o.Scheme = scheme
+		o.Client = client.Options{
+			Cache: &client.CacheOptions{
+				Unstructured: true,
+			},
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("unable to create client: %w", err)
+	}
+	if err := controller.SetupIndexForNamespace(ctx, c); err != nil {
+		return fmt.Errorf("failed to setup indexer for namespaces: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			ctrl.Log.Error(err, "failed to start cache")
+		}
+	}()
+	if !c.GetCache().WaitForCacheSync(ctx) {
+		return errors.New("failed to sync cache")
+	}
+
+	tenants, err := renderTargets(ctx, c.GetClient(), names)
+	if err != nil {
+		return err
+	}
+
+	r := controller.NewTenantReconciler(c.GetClient(), c.GetAPIReader(), &record.FakeRecorder{}, config.NewHolder(cfg))
+	for _, tenant := range tenants {
+		objs, err := r.Render(ctx, &tenant)
+		if err != nil {
+			return fmt.Errorf("failed to render tenant %s: %w", tenant.Name, err)
+		}
+		for _, obj := range objs {
+			if err := printRendered(w, obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func renderTargets(ctx context.Context, c client.Client, names []string) ([]cattagev1beta1.Tenant, error) {
+	tenants := make([]cattagev1beta1.Tenant, 0)
+	for _, f := range renderOptions.files {
+		ts, err := readTenants(f)
+		if err != nil {
+			return nil, err
+		}
+		tenants = append(tenants, ts...)
+	}
+	for _, name := range names {
+		tenant := cattagev1beta1.Tenant{}
+		if err := c.Get(ctx, client.ObjectKey{Name: name}, &tenant); err != nil {
+			return nil, fmt.Errorf("failed to get tenant %s: %w", name, err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	if len(renderOptions.files) != 0 || len(names) != 0 {
+		return tenants, nil
+	}
+
+	tenantList := &cattagev1beta1.TenantList{}
+	if err := c.List(ctx, tenantList); err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	return tenantList.Items, nil
+}
+
+func readTenants(path string) ([]cattagev1beta1.Tenant, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tenants := make([]cattagev1beta1.Tenant, 0)
+	dec := k8syaml.NewYAMLOrJSONDecoder(f, 4096)
+	for {
+		tenant := cattagev1beta1.Tenant{}
+		err := dec.Decode(&tenant)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+		}
+		if tenant.Name == "" {
+			continue
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+func printRendered(w io.Writer, obj controller.RenderedObject) error {
+	if !renderOptions.diff {
+		data, err := yaml.Marshal(obj.Desired.Object)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", data)
+		return err
+	}
+
+	name := obj.Desired.GetName()
+	if ns := obj.Desired.GetNamespace(); ns != "" {
+		name = ns + "/" + name
+	}
+	var diff string
+	if obj.Managed == nil {
+		diff = "(new object)\n"
+	} else if d := cmp.Diff(obj.Managed, obj.Desired.Object); d != "" {
+		diff = d
+	} else {
+		diff = "(no changes)\n"
+	}
+	_, err := fmt.Fprintf(w, "# %s %s\n%s\n", obj.Desired.GetKind(), name, diff)
+	return err
+}
+
+func init() {
+	fs := renderCmd.Flags()
+	fs.StringVar(&renderOptions.configFile, "config-file", defaultConfigPath, "Configuration file path")
+	fs.StringSliceVarP(&renderOptions.files, "filename", "f", nil, "Files that contain Tenant resources to render")
+	fs.BoolVar(&renderOptions.diff, "diff", false, "Show the differences from the fields managed by the controller instead of the rendered resources")
+
+	rootCmd.AddCommand(renderCmd)
+}
@@ -15,7 +15,6 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -27,30 +26,24 @@ func subMain(ns, addr string, port int) error {
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&options.zapOpts)))
 	logger := ctrl.Log.WithName("setup")
 
-	scheme := runtime.NewScheme()
-	if err := clientgoscheme.AddToScheme(scheme); err != nil {
-		return fmt.Errorf("unable to add client-go objects: %w", err)
-	}
-	if err := cattagev1beta1.AddToScheme(scheme); err != nil {
-		return fmt.Errorf("unable to add cattage objects: %w", err)
+	scheme, err := newScheme()
+	if err != nil {
+		return err
 	}
 
-	cfgData, err := os.ReadFile(options.configFile)
+	cfg, err := loadConfig(options.configFile)
 	if err != nil {
-		return fmt.Errorf("failed to read %s: %w", options.configFile, err)
-	}
-	cfg := &config.Config{}
-	if err := cfg.Load(cfgData); err != nil {
-		return fmt.Errorf("unable to load the configuration file: %w", err)
+		return err
 	}
 
+	cacheOpts, err := controller.CacheOptions()
+	if err != nil {
+		return err
+	}
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme: scheme,
-		Client: client.Options{
-			Cache: &client.CacheOptions{
-				Unstructured: true,
-			},
-		},
+		Client: controller.ClientOptions(),
+		Cache:  cacheOpts,
 		Metrics: metricsserver.Options{
 			BindAddress: options.metricsAddr,
 		},
@@ -71,19 +64,32 @@ func subMain(ns, addr string, port int) error {
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("invalid configurations: %w", err)
 	}
+	holder := config.NewHolder(cfg)
+	if err := mgr.Add(config.NewWatcher(options.configFile, holder)); err != nil {
+		return fmt.Errorf("unable to set up config watcher: %w", err)
+	}
 	ctx := ctrl.SetupSignalHandler()
 	if err := controller.SetupIndexForNamespace(ctx, mgr); err != nil {
 		return fmt.Errorf("failed to setup indexer for namespaces: %w", err)
 	}
 	if err := controller.NewTenantReconciler(
 		mgr.GetClient(),
-		cfg,
+		mgr.GetAPIReader(),
+		mgr.GetEventRecorderFor("cattage-controller"),
+		holder,
 	).SetupWithManager(mgr); err != nil {
 		return fmt.Errorf("unable to create Namespace controller: %w", err)
 	}
+	if err := controller.NewConfigMapReconciler(
+		mgr.GetClient(),
+		holder,
+	).SetupWithManager(mgr); err != nil {
+		return fmt.Errorf("unable to create ConfigMap controller: %w", err)
+	}
 
-	hooks.SetupTenantWebhook(mgr, admission.NewDecoder(scheme), cfg)
-	hooks.SetupApplicationWebhook(mgr, admission.NewDecoder(scheme), cfg)
+	hooks.SetupTenantWebhook(mgr, admission.NewDecoder(scheme), holder)
+	hooks.SetupApplicationWebhook(mgr, admission.NewDecoder(scheme), holder)
+	hooks.SetupSyncWindowWebhook(mgr, admission.NewDecoder(scheme))
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
@@ -99,3 +105,26 @@ func subMain(ns, addr string, port int) error {
 	}
 	return nil
 }
+
+func newScheme() (*runtime.Scheme, error) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("unable to add client-go objects: %w", err)
+	}
+	if err := cattagev1beta1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("unable to add cattage objects: %w", err)
+	}
+	return scheme, nil
+}
+
+func loadConfig(path string) (*config.Config, error) {
+	cfgData, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	cfg := &config.Config{}
+	if err := cfg.Load(cfgData); err != nil {
+		return nil, fmt.Errorf("unable to load the configuration file: %w", err)
+	}
+	return cfg, nil
+}
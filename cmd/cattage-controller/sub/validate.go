@@ -0,0 +1,41 @@
+package sub
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var validateOptions struct {
+	configFile string
+}
+
+var validateCmd = &cobra.Command{
+	Use:   "validate-config",
+	Short: "validate the configuration file",
+	Long: `Validate the configuration file without connecting to Kubernetes.
+
+The templates in the configuration file are executed with synthetic tenant data,
+and the results are checked to be decoded into the resources.`,
+	Args: cobra.NoArgs,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		cfg, err := loadConfig(validateOptions.configFile)
+		if err != nil {
+			return err
+		}
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("invalid configurations: %w", err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s is valid\n", validateOptions.configFile)
+		return nil
+	},
+}
+
+func init() {
+	fs := validateCmd.Flags()
+	fs.StringVar(&validateOptions.configFile, "config-file", defaultConfigPath, "Configuration file path")
+
+	rootCmd.AddCommand(validateCmd)
+}
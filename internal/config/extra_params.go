@@ -0,0 +1,186 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+)
+
+// TemplateOptions returns the options of the templates in the configuration and tenants.
+func (c *Config) TemplateOptions() []string {
+	if c.ExtraParams.StrictTemplates {
+		return []string{"missingkey=error"}
+	}
+	return nil
+}
+
+// DefaultExtraParams returns a copy of the extra parameters with the defaults in the schema applied.
+func (c *Config) DefaultExtraParams(params map[string]interface{}) map[string]interface{} {
+	result := (&cattagev1beta1.Params{Data: params}).DeepCopy().Data
+	if result == nil {
+		result = map[string]interface{}{}
+	}
+	if c.ExtraParams.Schema != nil {
+		applyDefaults(c.ExtraParams.Schema, result)
+	}
+	return result
+}
+
+// ValidateExtraParams validates the extra parameters against the schema in the configuration.
+// The defaults should be applied in advance with DefaultExtraParams.
+func (c *Config) ValidateExtraParams(params map[string]interface{}) error {
+	if c.ExtraParams.Schema == nil {
+		return nil
+	}
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	return validateValue(c.ExtraParams.Schema, "extraParams", params)
+}
+
+// validateExtraParamsSchema checks that the schema describes an object and that its defaults conform to it.
+func (c *Config) validateExtraParamsSchema(p *field.Path) field.ErrorList {
+	s := c.ExtraParams.Schema
+	if s == nil {
+		return nil
+	}
+	var allErrs field.ErrorList
+	if s.Type != "object" {
+		allErrs = append(allErrs, field.Invalid(p.Child("type"), s.Type, "should be object"))
+	}
+	if s.Default != nil {
+		allErrs = append(allErrs, field.Forbidden(p.Child("default"), "the default of the root is not supported"))
+	}
+	return append(allErrs, validateDefaults(p, s)...)
+}
+
+// validateDefaults checks that the defaults in the schema conform to the schemas where they are declared.
+func validateDefaults(p *field.Path, s *apiextensionsv1.JSONSchemaProps) field.ErrorList {
+	var allErrs field.ErrorList
+	if s.Default != nil {
+		if v, err := decodeJSON(s.Default); err != nil {
+			allErrs = append(allErrs, field.Invalid(p.Child("default"), string(s.Default.Raw), err.Error()))
+		} else if err := validateValue(s, "default", v); err != nil {
+			allErrs = append(allErrs, field.Invalid(p.Child("default"), string(s.Default.Raw), err.Error()))
+		}
+	}
+	for name, prop := range s.Properties {
+		allErrs = append(allErrs, validateDefaults(p.Child("properties").Key(name), &prop)...)
+	}
+	if s.Items != nil && s.Items.Schema != nil {
+		allErrs = append(allErrs, validateDefaults(p.Child("items"), s.Items.Schema)...)
+	}
+	if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+		allErrs = append(allErrs, validateDefaults(p.Child("additionalProperties"), s.AdditionalProperties.Schema)...)
+	}
+	return allErrs
+}
+
+// validateValue validates the value against the schema with the validator of OpenAPI.
+func validateValue(s *apiextensionsv1.JSONSchemaProps, root string, value interface{}) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	openapiSchema := &spec.Schema{}
+	if err := json.Unmarshal(data, openapiSchema); err != nil {
+		return fmt.Errorf("failed to convert the schema: %w", err)
+	}
+	result := validate.NewSchemaValidator(openapiSchema, nil, root, strfmt.Default).Validate(value)
+	return utilerrors.NewAggregate(result.Errors)
+}
+
+// applyDefaults sets the defaults in the schema to the missing properties of the value recursively.
+func applyDefaults(s *apiextensionsv1.JSONSchemaProps, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, prop := range s.Properties {
+			if _, ok := v[name]; !ok && prop.Default != nil {
+				d, err := decodeJSON(prop.Default)
+				if err != nil {
+					continue
+				}
+				v[name] = d
+			}
+			if child, ok := v[name]; ok {
+				applyDefaults(&prop, child)
+			}
+		}
+		if s.AdditionalProperties != nil && s.AdditionalProperties.Schema != nil {
+			for name, child := range v {
+				if _, ok := s.Properties[name]; !ok {
+					applyDefaults(s.AdditionalProperties.Schema, child)
+				}
+			}
+		}
+	case []interface{}:
+		if s.Items != nil && s.Items.Schema != nil {
+			for _, item := range v {
+				applyDefaults(s.Items.Schema, item)
+			}
+		}
+	}
+}
+
+// sampleExtraParams returns synthetic extra parameters for the validation of the templates.
+// They have the required properties and the defaults, so that strict templates can refer only to them without `index`.
+func (c *Config) sampleExtraParams() map[string]interface{} {
+	if c.ExtraParams.Schema == nil {
+		return map[string]interface{}{}
+	}
+	if m, ok := sampleValue(c.ExtraParams.Schema).(map[string]interface{}); ok {
+		return m
+	}
+	return map[string]interface{}{}
+}
+
+// sampleValue returns a synthetic value of the type in the schema.
+func sampleValue(s *apiextensionsv1.JSONSchemaProps) interface{} {
+	if s.Default != nil {
+		if v, err := decodeJSON(s.Default); err == nil {
+			return v
+		}
+	}
+	if len(s.Enum) != 0 {
+		if v, err := decodeJSON(&s.Enum[0]); err == nil {
+			return v
+		}
+	}
+	switch s.Type {
+	case "object":
+		m := make(map[string]interface{})
+		for _, name := range s.Required {
+			if prop, ok := s.Properties[name]; ok {
+				m[name] = sampleValue(&prop)
+			}
+		}
+		applyDefaults(s, m)
+		return m
+	case "array":
+		return []interface{}{}
+	case "string":
+		return "sample"
+	case "integer":
+		return int64(0)
+	case "number":
+		return float64(0)
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+func decodeJSON(v *apiextensionsv1.JSON) (interface{}, error) {
+	var out interface{}
+	if err := json.Unmarshal(v.Raw, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
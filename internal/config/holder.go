@@ -0,0 +1,46 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Holder holds the current configurations that can be replaced at runtime.
+type Holder struct {
+	current atomic.Pointer[Config]
+
+	mu       sync.Mutex
+	handlers []func(*Config)
+}
+
+// NewHolder returns a Holder that holds c.
+func NewHolder(c *Config) *Holder {
+	h := &Holder{}
+	h.current.Store(c)
+	return h
+}
+
+// Get returns the current configurations.
+// The returned value must not be modified.
+func (h *Holder) Get() *Config {
+	return h.current.Load()
+}
+
+// Set replaces the current configurations with c and calls the handlers registered by OnChange.
+func (h *Holder) Set(c *Config) {
+	h.current.Store(c)
+
+	h.mu.Lock()
+	handlers := h.handlers
+	h.mu.Unlock()
+	for _, f := range handlers {
+		f(c)
+	}
+}
+
+// OnChange registers f to be called after the configurations are replaced.
+func (h *Holder) OnChange(f func(*Config)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers = append(h.handlers, f)
+}
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	accorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	acrbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// sampleRole has the same fields as the roles passed to the templates by the controller.
+type sampleRole struct {
+	Name        string
+	ExtraParams map[string]interface{}
+}
+
+// sampleDestination has the same fields as the destinations passed to the template for AppProject by the controller.
+type sampleDestination struct {
+	Name       string
+	Server     string
+	Namespaces []string
+}
+
+// sampleNamespaceParams returns synthetic data passed to the templates for namespaces.
+func (c *Config) sampleNamespaceParams(roles map[string][]sampleRole) any {
+	return struct {
+		Name        string
+		Roles       map[string][]sampleRole
+		ExtraParams map[string]interface{}
+	}{
+		Name:        "sample-tenant",
+		Roles:       roles,
+		ExtraParams: c.sampleExtraParams(),
+	}
+}
+
+// sampleAppProjectParams returns synthetic data passed to the template for AppProject.
+func (c *Config) sampleAppProjectParams(roles map[string][]sampleRole) any {
+	return struct {
+		Name         string
+		Ancestors    []string
+		Namespaces   []string
+		Roles        map[string][]sampleRole
+		Repositories []string
+		Destinations []sampleDestination
+		ExtraParams  map[string]interface{}
+	}{
+		Name:         "sample-tenant",
+		Ancestors:    []string{"sample-parent"},
+		Namespaces:   []string{"sample-delegated-root", "sample-root", "sample-sub"},
+		Roles:        roles,
+		Repositories: []string{"https://github.com/example/*"},
+		Destinations: []sampleDestination{
+			{Name: "sample-cluster", Server: "https://sample-cluster.example.com", Namespaces: []string{"sample-root"}},
+		},
+		ExtraParams: c.sampleExtraParams(),
+	}
+}
+
+// sampleRepositoryCredentialParams returns synthetic data passed to the template for repository credentials.
+// The template is not given roles.
+func (c *Config) sampleRepositoryCredentialParams(map[string][]sampleRole) any {
+	return struct {
+		Name        string
+		URL         string
+		Secret      map[string]string
+		ExtraParams map[string]interface{}
+	}{
+		Name:        "sample-tenant",
+		URL:         "https://github.com/example/sample.git",
+		Secret:      map[string]string{"username": "sample-user", "password": "sample-password"},
+		ExtraParams: c.sampleExtraParams(),
+	}
+}
+
+func (c *Config) sampleRoles() map[string][]sampleRole {
+	return map[string][]sampleRole{
+		"admin": {
+			{Name: "sample-delegated-tenant", ExtraParams: c.sampleExtraParams()},
+		},
+	}
+}
+
+// executeSample parses and executes the template with the synthetic data, and returns the output with the sample roles.
+// The template is also executed with empty roles as the controller does for tenants without delegates,
+// so that strict templates referring to the roles without `index` are detected.
+// The extra parameters are always the minimal ones that tenants can have.
+func (c *Config) executeSample(p *field.Path, text string, data func(roles map[string][]sampleRole) any) ([]byte, *field.Error) {
+	tpl, err := template.New(p.String()).Option(c.TemplateOptions()...).Parse(text)
+	if err != nil {
+		return nil, field.Invalid(p, field.OmitValueType{}, fmt.Sprintf("failed to parse template: %v", err))
+	}
+	var result []byte
+	for i, roles := range []map[string][]sampleRole{c.sampleRoles(), {}} {
+		var buf bytes.Buffer
+		if err := tpl.Execute(&buf, data(roles)); err != nil {
+			return nil, field.Invalid(p, field.OmitValueType{}, fmt.Sprintf("failed to execute template: %v", err))
+		}
+		if i == 0 {
+			result = buf.Bytes()
+		}
+	}
+	return result, nil
+}
+
+// validateTypedTemplate checks that the output of the template decodes into obj
+// and that the apiVersion and kind match gvk if they are specified.
+// Unknown fields are ignored as the controller does when it decodes the output.
+func (c *Config) validateTypedTemplate(p *field.Path, text string, data func(map[string][]sampleRole) any, gvk schema.GroupVersionKind, obj any) field.ErrorList {
+	buf, ferr := c.executeSample(p, text, data)
+	if ferr != nil {
+		return field.ErrorList{ferr}
+	}
+
+	var allErrs field.ErrorList
+	if err := k8syaml.Unmarshal(buf, obj); err != nil {
+		allErrs = append(allErrs, field.Invalid(p, field.OmitValueType{}, fmt.Sprintf("failed to decode %s: %v", gvk.Kind, err)))
+		return allErrs
+	}
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(buf, &u.Object); err != nil {
+		allErrs = append(allErrs, field.Invalid(p, field.OmitValueType{}, fmt.Sprintf("failed to decode %s: %v", gvk.Kind, err)))
+		return allErrs
+	}
+	if apiVersion := u.GetAPIVersion(); apiVersion != "" && apiVersion != gvk.GroupVersion().String() {
+		allErrs = append(allErrs, field.Invalid(p.Child("apiVersion"), apiVersion, fmt.Sprintf("should be %s", gvk.GroupVersion().String())))
+	}
+	if kind := u.GetKind(); kind != "" && kind != gvk.Kind {
+		allErrs = append(allErrs, field.Invalid(p.Child("kind"), kind, fmt.Sprintf("should be %s", gvk.Kind)))
+	}
+	return allErrs
+}
+
+func (c *Config) validateRoleBindingTemplate(p *field.Path, text string) field.ErrorList {
+	gvk := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}
+	return c.validateTypedTemplate(p, text, c.sampleNamespaceParams, gvk, &acrbacv1.RoleBindingApplyConfiguration{})
+}
+
+func (c *Config) validateResourceQuotaTemplate(p *field.Path, text string) field.ErrorList {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "ResourceQuota"}
+	return c.validateTypedTemplate(p, text, c.sampleNamespaceParams, gvk, &accorev1.ResourceQuotaApplyConfiguration{})
+}
+
+func (c *Config) validateLimitRangeTemplate(p *field.Path, text string) field.ErrorList {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "LimitRange"}
+	return c.validateTypedTemplate(p, text, c.sampleNamespaceParams, gvk, &accorev1.LimitRangeApplyConfiguration{})
+}
+
+// ValidateTenantTemplates checks the templates for ResourceQuota and LimitRange in the spec of a tenant.
+func (c *Config) ValidateTenantTemplates(spec *cattagev1beta1.TenantSpec) field.ErrorList {
+	var allErrs field.ErrorList
+	p := field.NewPath("spec")
+	if spec.ResourceQuotaTemplate != "" {
+		allErrs = append(allErrs, c.validateResourceQuotaTemplate(p.Child("resourceQuotaTemplate"), spec.ResourceQuotaTemplate)...)
+	}
+	if spec.LimitRangeTemplate != "" {
+		allErrs = append(allErrs, c.validateLimitRangeTemplate(p.Child("limitRangeTemplate"), spec.LimitRangeTemplate)...)
+	}
+	return allErrs
+}
+
+func (c *Config) validateRepositoryCredentialTemplate(p *field.Path, text string) field.ErrorList {
+	gvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	return c.validateTypedTemplate(p, text, c.sampleRepositoryCredentialParams, gvk, &accorev1.SecretApplyConfiguration{})
+}
+
+// validateResourceTemplate checks that the output of the template is empty or a resource with its name.
+func (c *Config) validateResourceTemplate(p *field.Path, text string) field.ErrorList {
+	buf, ferr := c.executeSample(p, text, c.sampleNamespaceParams)
+	if ferr != nil {
+		return field.ErrorList{ferr}
+	}
+	if len(bytes.TrimSpace(buf)) == 0 {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(buf, &u.Object); err != nil {
+		return append(allErrs, field.Invalid(p, field.OmitValueType{}, fmt.Sprintf("failed to decode resource: %v", err)))
+	}
+	if u.GetAPIVersion() == "" {
+		allErrs = append(allErrs, field.Required(p.Child("apiVersion"), ""))
+	}
+	if u.GetKind() == "" {
+		allErrs = append(allErrs, field.Required(p.Child("kind"), ""))
+	}
+	if u.GetName() == "" {
+		allErrs = append(allErrs, field.Required(p.Child("metadata", "name"), ""))
+	}
+	return allErrs
+}
+
+// validateAppProjectTemplate checks that the output of the template is an argoproj.io/v1alpha1 AppProject.
+func (c *Config) validateAppProjectTemplate(p *field.Path, text string) field.ErrorList {
+	buf, ferr := c.executeSample(p, text, c.sampleAppProjectParams)
+	if ferr != nil {
+		return field.ErrorList{ferr}
+	}
+
+	var allErrs field.ErrorList
+	u := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(buf, &u.Object); err != nil {
+		return append(allErrs, field.Invalid(p, field.OmitValueType{}, fmt.Sprintf("failed to decode AppProject: %v", err)))
+	}
+	if u.GetAPIVersion() != "argoproj.io/v1alpha1" {
+		allErrs = append(allErrs, field.Invalid(p.Child("apiVersion"), u.GetAPIVersion(), "should be argoproj.io/v1alpha1"))
+	}
+	if u.GetKind() != "AppProject" {
+		allErrs = append(allErrs, field.Invalid(p.Child("kind"), u.GetKind(), "should be AppProject"))
+	}
+	if spec, ok := u.Object["spec"]; ok {
+		if _, ok := spec.(map[string]interface{}); !ok {
+			return append(allErrs, field.Invalid(p.Child("spec"), field.OmitValueType{}, "should be an object"))
+		}
+	}
+	val, found, err := unstructured.NestedFieldNoCopy(u.Object, "spec", "syncWindows")
+	if err != nil {
+		return append(allErrs, field.Invalid(p.Child("spec", "syncWindows"), field.OmitValueType{}, err.Error()))
+	}
+	if found {
+		data, err := json.Marshal(val)
+		if err != nil {
+			return append(allErrs, field.Invalid(p.Child("spec", "syncWindows"), field.OmitValueType{}, err.Error()))
+		}
+		var sws cattagev1beta1.SyncWindows
+		if err := yaml.Unmarshal(data, &sws); err != nil {
+			allErrs = append(allErrs, field.Invalid(p.Child("spec", "syncWindows"), field.OmitValueType{}, fmt.Sprintf("failed to decode sync windows: %v", err)))
+		}
+	}
+	return allErrs
+}
+
+// validateConfigMapName checks that the name template of the ConfigMap yields a valid name,
+// and a distinct name for each application controller if `perController` is true.
+func validateConfigMapName(p *field.Path, cm ConfigMapConfig) field.ErrorList {
+	var allErrs field.ErrorList
+	controllerNames := []string{"sample-controller-a"}
+	if cm.PerController {
+		controllerNames = append(controllerNames, "sample-controller-b")
+	}
+	names := make(map[string]struct{})
+	for _, controllerName := range controllerNames {
+		name, err := cm.ConfigMapName(controllerName)
+		if err != nil {
+			return append(allErrs, field.Invalid(p, cm.Name, fmt.Sprintf("failed to render name: %v", err)))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(p, cm.Name, msg))
+		}
+		names[name] = struct{}{}
+	}
+	if cm.PerController && len(names) == 1 {
+		allErrs = append(allErrs, field.Invalid(p, cm.Name, "should contain {{ .ControllerName }} when perController is true"))
+	}
+	return allErrs
+}
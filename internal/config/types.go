@@ -2,9 +2,18 @@ package config
 
 import (
 	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
 
+	"github.com/cybozu-go/cattage/internal/constants"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	v1annotationvalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	v1labelvalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/yaml"
@@ -12,8 +21,11 @@ import (
 
 // Config represents the configuration file of cattage.
 type Config struct {
-	Namespace NamespaceConfig `json:"namespace,omitempty"`
-	ArgoCD    ArgoCDConfig    `json:"argocd,omitempty"`
+	Namespace   NamespaceConfig   `json:"namespace,omitempty"`
+	ArgoCD      ArgoCDConfig      `json:"argocd,omitempty"`
+	Isolation   IsolationConfig   `json:"isolation,omitempty"`
+	Delegation  DelegationConfig  `json:"delegation,omitempty"`
+	ExtraParams ExtraParamsConfig `json:"extraParams,omitempty"`
 }
 
 // NamespaceConfig represents the configuration about Namespaces
@@ -28,6 +40,26 @@ type NamespaceConfig struct {
 
 	// RoleBindingTemplate is a template for RoleBinding resource that is created on all namespaces belonging to a tenant
 	RoleBindingTemplate string `json:"roleBindingTemplate"`
+
+	// ResourceQuotaTemplate is a template for ResourceQuota resource that is created on root namespaces belonging to a tenant
+	// This may be overridden by `resourceQuotaTemplate` of a tenant resource.
+	ResourceQuotaTemplate string `json:"resourceQuotaTemplate,omitempty"`
+
+	// LimitRangeTemplate is a template for LimitRange resource that is created on root namespaces belonging to a tenant
+	// This may be overridden by `limitRangeTemplate` of a tenant resource.
+	LimitRangeTemplate string `json:"limitRangeTemplate,omitempty"`
+
+	// ResourceTemplates are templates for arbitrary resources that are created on root namespaces belonging to a tenant
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates,omitempty"`
+}
+
+// ResourceTemplate represents a named template for a resource that is created on root namespaces
+type ResourceTemplate struct {
+	// Name is the unique name of this template
+	Name string `json:"name"`
+
+	// Template is a template for the resource
+	Template string `json:"template"`
 }
 
 // ArgoCDConfig represents the configuration about Argo CD
@@ -40,6 +72,223 @@ type ArgoCDConfig struct {
 
 	// PreventAppCreationInArgoCDNamespace is a flag to prevent creating applications in the Argo CD namespace
 	PreventAppCreationInArgoCDNamespace bool `json:"preventAppCreationInArgoCDNamespace"`
+
+	// ApplicationControllers are the names of sharded application controllers other than the default one.
+	// If empty, `controllerName` of tenants is not restricted.
+	ApplicationControllers []string `json:"applicationControllers,omitempty"`
+
+	// Sharding is the policy to assign tenants without `controllerName` to the application controllers.
+	Sharding ShardingConfig `json:"sharding,omitempty"`
+
+	// ConfigMaps are the ConfigMaps listing the namespaces of tenants for the components of Argo CD.
+	// If empty, DefaultConfigMaps are generated.
+	ConfigMaps []ConfigMapConfig `json:"configMaps,omitempty"`
+
+	// CompactNamespaces is a flag to replace the namespaces matching `argocd.namespacePatterns` of tenants with the patterns
+	// in the AppProjects and the ConfigMaps.
+	CompactNamespaces bool `json:"compactNamespaces,omitempty"`
+
+	// Clusters are the clusters that can be specified in `argocd.destinations` of tenants.
+	// If empty, only the cluster where Argo CD runs is allowed.
+	Clusters []ClusterConfig `json:"clusters,omitempty"`
+
+	// Repositories is the configuration about the source repositories of tenants.
+	Repositories RepositoriesConfig `json:"repositories,omitempty"`
+
+	// ResourcePolicies are named policies of the resources that tenants can deploy, referred to by `argocd.resourcePolicy.profile` of tenants.
+	ResourcePolicies []ResourcePolicyConfig `json:"resourcePolicies,omitempty"`
+
+	// DefaultResourcePolicy is the name of the resource policy for tenants that do not refer to one.
+	// If empty, no resource policy is applied to such tenants.
+	DefaultResourcePolicy string `json:"defaultResourcePolicy,omitempty"`
+
+	// DelegateGroups are templates of the groups that are given the read-only roles of delegated tenants
+	// unless `groups` of the delegates are specified in tenants.
+	// They are rendered with the name of the delegated tenant as `.Name`.
+	DelegateGroups []string `json:"delegateGroups,omitempty"`
+}
+
+// ResourcePolicyConfig represents a named policy of the resources that tenants can deploy.
+// The lists are merged into those of the AppProjects rendered from the template.
+type ResourcePolicyConfig struct {
+	// Name is the unique name of this policy
+	Name string `json:"name"`
+
+	// TenantSelector selects the tenants that can use this policy by their labels.
+	// If nil, all tenants can use this policy.
+	TenantSelector *metav1.LabelSelector `json:"tenantSelector,omitempty"`
+
+	// ClusterResourceWhitelist is the cluster-scoped resources that are allowed
+	ClusterResourceWhitelist []metav1.GroupKind `json:"clusterResourceWhitelist,omitempty"`
+
+	// ClusterResourceBlacklist is the cluster-scoped resources that are denied
+	ClusterResourceBlacklist []metav1.GroupKind `json:"clusterResourceBlacklist,omitempty"`
+
+	// NamespaceResourceWhitelist is the namespaced resources that are allowed
+	NamespaceResourceWhitelist []metav1.GroupKind `json:"namespaceResourceWhitelist,omitempty"`
+
+	// NamespaceResourceBlacklist is the namespaced resources that are denied
+	NamespaceResourceBlacklist []metav1.GroupKind `json:"namespaceResourceBlacklist,omitempty"`
+}
+
+// RepositoriesConfig represents the configuration about the source repositories of tenants
+type RepositoriesConfig struct {
+	// AllowedPatterns are glob patterns of the repository URLs that all tenants can use.
+	// If neither `allowedPatterns` nor `rules` is specified, any repository is allowed.
+	AllowedPatterns []string `json:"allowedPatterns,omitempty"`
+
+	// Rules allow the selected tenants to use the repositories in addition to `allowedPatterns`.
+	Rules []RepositoryRule `json:"rules,omitempty"`
+
+	// CredentialTemplate is a template for Secret resources of the repository credentials for Argo CD
+	// that are created from `argocd.repositoryCredentials` of tenants.
+	// If empty, the credentials are not created.
+	CredentialTemplate string `json:"credentialTemplate,omitempty"`
+}
+
+// RepositoryRule allows the selected tenants to use the repositories
+type RepositoryRule struct {
+	// TenantSelector selects tenants by their labels
+	TenantSelector metav1.LabelSelector `json:"tenantSelector"`
+
+	// AllowedPatterns are glob patterns of the repository URLs
+	AllowedPatterns []string `json:"allowedPatterns"`
+}
+
+// ClusterConfig represents a cluster where the applications of tenants can be deployed
+type ClusterConfig struct {
+	// Name is the name of the cluster in Argo CD
+	Name string `json:"name,omitempty"`
+
+	// Server is the URL of the API server of the cluster
+	Server string `json:"server,omitempty"`
+}
+
+// ConfigMapConfig represents a ConfigMap listing the namespaces of tenants
+type ConfigMapConfig struct {
+	// Name is a template for the name of the ConfigMap.
+	// `{{ .ControllerName }}` is replaced with the name of the application controller if `perController` is true.
+	Name string `json:"name"`
+
+	// Namespace is the namespace of the ConfigMap.
+	// If empty, the namespace of Argo CD is used.
+	Namespace string `json:"namespace,omitempty"`
+
+	// Key is the key of the data of the ConfigMap.
+	// If empty, `application.namespaces` is used.
+	Key string `json:"key,omitempty"`
+
+	// Separator is the separator between the namespaces.
+	// If empty, `,` is used.
+	Separator string `json:"separator,omitempty"`
+
+	// PerController is a flag to generate a ConfigMap for each application controller that tenants are assigned to.
+	// Tenants migrating between controllers are not listed in the ConfigMaps.
+	PerController bool `json:"perController,omitempty"`
+
+	// TenantSelector selects the tenants to be listed by their labels.
+	// If nil, all tenants are listed.
+	TenantSelector *metav1.LabelSelector `json:"tenantSelector,omitempty"`
+
+	// Controllers are the names of the application controllers whose tenants are listed.
+	// If empty, tenants are listed regardless of their controllers.
+	Controllers []string `json:"controllers,omitempty"`
+}
+
+// DefaultConfigMaps are the ConfigMaps generated if `argocd.configMaps` is not specified.
+var DefaultConfigMaps = []ConfigMapConfig{
+	{
+		Name:          "{{ .ControllerName }}-application-controller-cm",
+		PerController: true,
+	},
+	{
+		Name: "all-tenant-namespaces-cm",
+	},
+}
+
+// DefaultConfigMapKey is the key of the data of the ConfigMaps if not specified.
+const DefaultConfigMapKey = "application.namespaces"
+
+// DefaultConfigMapSeparator is the separator between the namespaces if not specified.
+const DefaultConfigMapSeparator = ","
+
+// ShardingPolicy is the policy to assign tenants to the application controllers.
+type ShardingPolicy string
+
+const (
+	// ShardingPolicyExplicit assigns tenants without `controllerName` to the default controller.
+	ShardingPolicyExplicit ShardingPolicy = "Explicit"
+
+	// ShardingPolicyLabelSelector assigns tenants to the controller of the first rule that selects them.
+	ShardingPolicyLabelSelector ShardingPolicy = "LabelSelector"
+
+	// ShardingPolicyBalanced assigns tenants to the least loaded controller.
+	ShardingPolicyBalanced ShardingPolicy = "Balanced"
+)
+
+// BalanceBy is the measure of the load of application controllers.
+type BalanceBy string
+
+const (
+	BalanceByNamespaces   BalanceBy = "Namespaces"
+	BalanceByApplications BalanceBy = "Applications"
+)
+
+// ShardingConfig represents the configuration about sharding of application controllers
+type ShardingConfig struct {
+	// Policy is the policy to assign tenants to the application controllers.
+	// If empty, `Explicit` is used.
+	Policy ShardingPolicy `json:"policy,omitempty"`
+
+	// Rules are the rules for the `LabelSelector` policy.
+	// Tenants that no rule selects are assigned to the default controller.
+	Rules []ShardingRule `json:"rules,omitempty"`
+
+	// BalanceBy is the measure of the load for the `Balanced` policy.
+	// If empty, `Namespaces` is used.
+	BalanceBy BalanceBy `json:"balanceBy,omitempty"`
+
+	// MigrationGracePeriod is the time to wait after the namespaces of a tenant are removed from the old application controller
+	// before they are added to the new one.
+	// If zero, the namespaces are moved at once.
+	MigrationGracePeriod metav1.Duration `json:"migrationGracePeriod,omitempty"`
+}
+
+// ShardingRule assigns the selected tenants to an application controller
+type ShardingRule struct {
+	// TenantSelector selects tenants by their labels
+	TenantSelector metav1.LabelSelector `json:"tenantSelector"`
+
+	// ControllerName is the name of the application controller
+	ControllerName string `json:"controllerName"`
+}
+
+// IsolationConfig represents the configuration about network isolation between tenants
+type IsolationConfig struct {
+	// Enabled is a flag to create NetworkPolicies that deny traffic from other tenants on all namespaces belonging to a tenant
+	Enabled bool `json:"enabled"`
+
+	// AllowedNamespaces are namespaces that are allowed to access all namespaces belonging to tenants
+	AllowedNamespaces []string `json:"allowedNamespaces,omitempty"`
+}
+
+// DelegationConfig represents the configuration about delegation between tenants
+type DelegationConfig struct {
+	// AllowedRoles are roles that can be specified in `delegates` of tenants.
+	// If empty, any role is allowed.
+	AllowedRoles []string `json:"allowedRoles,omitempty"`
+}
+
+// ExtraParamsConfig represents the configuration about `extraParams` of tenants
+type ExtraParamsConfig struct {
+	// Schema is an OpenAPI v3 schema of `extraParams` of tenants.
+	// The defaults in the schema are applied before the templates are rendered.
+	// If nil, `extraParams` are not validated.
+	Schema *apiextensionsv1.JSONSchemaProps `json:"schema,omitempty"`
+
+	// StrictTemplates is a flag to make the templates fail on missing keys of maps such as `.ExtraParams` and `.Roles`
+	// instead of rendering `<no value>`.
+	StrictTemplates bool `json:"strictTemplates,omitempty"`
 }
 
 // Validate validates the configurations.
@@ -51,6 +300,31 @@ func (c *Config) Validate() error {
 
 	if len(c.Namespace.RoleBindingTemplate) == 0 {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("namespace", "roleBindingTemplate"), c.Namespace.RoleBindingTemplate, "should not be empty"))
+	} else {
+		allErrs = append(allErrs, c.validateRoleBindingTemplate(field.NewPath("namespace", "roleBindingTemplate"), c.Namespace.RoleBindingTemplate)...)
+	}
+	if len(c.Namespace.ResourceQuotaTemplate) != 0 {
+		allErrs = append(allErrs, c.validateResourceQuotaTemplate(field.NewPath("namespace", "resourceQuotaTemplate"), c.Namespace.ResourceQuotaTemplate)...)
+	}
+	if len(c.Namespace.LimitRangeTemplate) != 0 {
+		allErrs = append(allErrs, c.validateLimitRangeTemplate(field.NewPath("namespace", "limitRangeTemplate"), c.Namespace.LimitRangeTemplate)...)
+	}
+
+	names := make(map[string]struct{})
+	for i, rt := range c.Namespace.ResourceTemplates {
+		p := field.NewPath("namespace", "resourceTemplates").Index(i)
+		for _, msg := range validation.IsDNS1123Label(rt.Name) {
+			allErrs = append(allErrs, field.Invalid(p.Child("name"), rt.Name, msg))
+		}
+		if _, ok := names[rt.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(p.Child("name"), rt.Name))
+		}
+		names[rt.Name] = struct{}{}
+		if len(rt.Template) == 0 {
+			allErrs = append(allErrs, field.Invalid(p.Child("template"), rt.Template, "should not be empty"))
+		} else {
+			allErrs = append(allErrs, c.validateResourceTemplate(p.Child("template"), rt.Template)...)
+		}
 	}
 
 	for _, msg := range validation.IsDNS1123Subdomain(c.ArgoCD.Namespace) {
@@ -58,6 +332,79 @@ func (c *Config) Validate() error {
 	}
 	if len(c.ArgoCD.AppProjectTemplate) == 0 {
 		allErrs = append(allErrs, field.Invalid(field.NewPath("argocd", "appProjectTemplate"), c.ArgoCD.AppProjectTemplate, "should not be empty"))
+	} else {
+		allErrs = append(allErrs, c.validateAppProjectTemplate(field.NewPath("argocd", "appProjectTemplate"), c.ArgoCD.AppProjectTemplate)...)
+	}
+
+	controllers := make(map[string]struct{})
+	for i, name := range c.ArgoCD.ApplicationControllers {
+		p := field.NewPath("argocd", "applicationControllers").Index(i)
+		for _, msg := range validation.IsDNS1123Label(name) {
+			allErrs = append(allErrs, field.Invalid(p, name, msg))
+		}
+		if _, ok := controllers[name]; ok {
+			allErrs = append(allErrs, field.Duplicate(p, name))
+		}
+		controllers[name] = struct{}{}
+	}
+
+	allErrs = append(allErrs, c.validateSharding(field.NewPath("argocd", "sharding"))...)
+	allErrs = append(allErrs, c.validateConfigMaps(field.NewPath("argocd", "configMaps"))...)
+
+	clusterNames := make(map[string]struct{})
+	servers := make(map[string]struct{})
+	for i, cluster := range c.ArgoCD.Clusters {
+		p := field.NewPath("argocd", "clusters").Index(i)
+		if len(cluster.Name) == 0 && len(cluster.Server) == 0 {
+			allErrs = append(allErrs, field.Required(p, "name or server is required"))
+		}
+		if len(cluster.Name) != 0 {
+			if _, ok := clusterNames[cluster.Name]; ok {
+				allErrs = append(allErrs, field.Duplicate(p.Child("name"), cluster.Name))
+			}
+			clusterNames[cluster.Name] = struct{}{}
+		}
+		if len(cluster.Server) != 0 {
+			if _, ok := servers[cluster.Server]; ok {
+				allErrs = append(allErrs, field.Duplicate(p.Child("server"), cluster.Server))
+			}
+			servers[cluster.Server] = struct{}{}
+		}
+	}
+
+	allErrs = append(allErrs, c.validateRepositories(field.NewPath("argocd", "repositories"))...)
+	allErrs = append(allErrs, c.validateResourcePolicies(field.NewPath("argocd"))...)
+
+	for i, text := range c.ArgoCD.DelegateGroups {
+		p := field.NewPath("argocd", "delegateGroups").Index(i)
+		group, err := renderDelegateGroup(text, "sample-tenant")
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(p, text, fmt.Sprintf("failed to render group: %v", err)))
+			continue
+		}
+		if group == "" {
+			allErrs = append(allErrs, field.Invalid(p, text, "should not render an empty group"))
+		}
+	}
+
+	for i, ns := range c.Isolation.AllowedNamespaces {
+		for _, msg := range validation.IsDNS1123Label(ns) {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("isolation", "allowedNamespaces").Index(i), ns, msg))
+		}
+	}
+
+	allErrs = append(allErrs, c.validateExtraParamsSchema(field.NewPath("extraParams", "schema"))...)
+
+	roles := make(map[string]struct{})
+	for i, role := range c.Delegation.AllowedRoles {
+		p := field.NewPath("delegation", "allowedRoles").Index(i)
+		if len(role) == 0 {
+			allErrs = append(allErrs, field.Invalid(p, role, "should not be empty"))
+		}
+		if _, ok := roles[role]; ok {
+			allErrs = append(allErrs, field.Duplicate(p, role))
+		}
+		roles[role] = struct{}{}
 	}
 
 	if len(allErrs) != 0 {
@@ -67,6 +414,328 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+func (c *Config) validateSharding(p *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	sharding := c.ArgoCD.Sharding
+	switch sharding.Policy {
+	case "", ShardingPolicyExplicit:
+	case ShardingPolicyLabelSelector:
+		if len(sharding.Rules) == 0 {
+			allErrs = append(allErrs, field.Required(p.Child("rules"), "rules are required for the LabelSelector policy"))
+		}
+	case ShardingPolicyBalanced:
+		if len(c.ArgoCD.ApplicationControllers) == 0 {
+			allErrs = append(allErrs, field.Required(field.NewPath("argocd", "applicationControllers"), "application controllers are required for the Balanced policy"))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(p.Child("policy"), sharding.Policy, []ShardingPolicy{ShardingPolicyExplicit, ShardingPolicyLabelSelector, ShardingPolicyBalanced}))
+	}
+
+	for i, rule := range sharding.Rules {
+		rp := p.Child("rules").Index(i)
+		allErrs = append(allErrs, v1labelvalidation.ValidateLabelSelector(&rule.TenantSelector, v1labelvalidation.LabelSelectorValidationOptions{}, rp.Child("tenantSelector"))...)
+		if len(rule.ControllerName) == 0 {
+			allErrs = append(allErrs, field.Invalid(rp.Child("controllerName"), rule.ControllerName, "should not be empty"))
+		} else if !c.IsKnownController(rule.ControllerName) {
+			allErrs = append(allErrs, field.NotFound(rp.Child("controllerName"), rule.ControllerName))
+		}
+	}
+
+	switch sharding.BalanceBy {
+	case "", BalanceByNamespaces, BalanceByApplications:
+	default:
+		allErrs = append(allErrs, field.NotSupported(p.Child("balanceBy"), sharding.BalanceBy, []BalanceBy{BalanceByNamespaces, BalanceByApplications}))
+	}
+
+	if sharding.MigrationGracePeriod.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(p.Child("migrationGracePeriod"), sharding.MigrationGracePeriod.String(), "should not be negative"))
+	}
+	return allErrs
+}
+
+func (c *Config) validateConfigMaps(p *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := make(map[string]struct{})
+	for i, cm := range c.ArgoCD.ConfigMaps {
+		cp := p.Index(i)
+		if len(cm.Name) == 0 {
+			allErrs = append(allErrs, field.Invalid(cp.Child("name"), cm.Name, "should not be empty"))
+		} else {
+			allErrs = append(allErrs, validateConfigMapName(cp.Child("name"), cm)...)
+			name, err := cm.ConfigMapName(constants.DefaultApplicationControllerName)
+			if err == nil {
+				key := c.ConfigMapNamespace(cm) + "/" + name
+				if _, ok := names[key]; ok {
+					allErrs = append(allErrs, field.Duplicate(cp.Child("name"), name))
+				}
+				names[key] = struct{}{}
+			}
+		}
+		if len(cm.Namespace) != 0 {
+			for _, msg := range validation.IsDNS1123Label(cm.Namespace) {
+				allErrs = append(allErrs, field.Invalid(cp.Child("namespace"), cm.Namespace, msg))
+			}
+		}
+		if len(cm.Key) != 0 {
+			for _, msg := range validation.IsConfigMapKey(cm.Key) {
+				allErrs = append(allErrs, field.Invalid(cp.Child("key"), cm.Key, msg))
+			}
+		}
+		if cm.TenantSelector != nil {
+			allErrs = append(allErrs, v1labelvalidation.ValidateLabelSelector(cm.TenantSelector, v1labelvalidation.LabelSelectorValidationOptions{}, cp.Child("tenantSelector"))...)
+		}
+		for j, name := range cm.Controllers {
+			if !c.IsKnownController(name) {
+				allErrs = append(allErrs, field.NotFound(cp.Child("controllers").Index(j), name))
+			}
+		}
+	}
+	return allErrs
+}
+
+func (c *Config) validateRepositories(p *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	validatePatterns := func(pp *field.Path, patterns []string) {
+		for i, pattern := range patterns {
+			if len(pattern) == 0 {
+				allErrs = append(allErrs, field.Invalid(pp.Index(i), pattern, "should not be empty"))
+			} else if _, err := path.Match(pattern, ""); err != nil {
+				allErrs = append(allErrs, field.Invalid(pp.Index(i), pattern, err.Error()))
+			}
+		}
+	}
+	repos := c.ArgoCD.Repositories
+	validatePatterns(p.Child("allowedPatterns"), repos.AllowedPatterns)
+	for i, rule := range repos.Rules {
+		rp := p.Child("rules").Index(i)
+		allErrs = append(allErrs, v1labelvalidation.ValidateLabelSelector(&rule.TenantSelector, v1labelvalidation.LabelSelectorValidationOptions{}, rp.Child("tenantSelector"))...)
+		if len(rule.AllowedPatterns) == 0 {
+			allErrs = append(allErrs, field.Required(rp.Child("allowedPatterns"), ""))
+		}
+		validatePatterns(rp.Child("allowedPatterns"), rule.AllowedPatterns)
+	}
+	if len(repos.CredentialTemplate) != 0 {
+		allErrs = append(allErrs, c.validateRepositoryCredentialTemplate(p.Child("credentialTemplate"), repos.CredentialTemplate)...)
+	}
+	return allErrs
+}
+
+func (c *Config) validateResourcePolicies(p *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	validateGroupKinds := func(gp *field.Path, gks []metav1.GroupKind) {
+		for i, gk := range gks {
+			if len(gk.Kind) == 0 {
+				allErrs = append(allErrs, field.Required(gp.Index(i).Child("kind"), ""))
+			}
+		}
+	}
+	names := make(map[string]struct{})
+	for i, policy := range c.ArgoCD.ResourcePolicies {
+		pp := p.Child("resourcePolicies").Index(i)
+		for _, msg := range validation.IsDNS1123Label(policy.Name) {
+			allErrs = append(allErrs, field.Invalid(pp.Child("name"), policy.Name, msg))
+		}
+		if _, ok := names[policy.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(pp.Child("name"), policy.Name))
+		}
+		names[policy.Name] = struct{}{}
+		if policy.TenantSelector != nil {
+			allErrs = append(allErrs, v1labelvalidation.ValidateLabelSelector(policy.TenantSelector, v1labelvalidation.LabelSelectorValidationOptions{}, pp.Child("tenantSelector"))...)
+		}
+		validateGroupKinds(pp.Child("clusterResourceWhitelist"), policy.ClusterResourceWhitelist)
+		validateGroupKinds(pp.Child("clusterResourceBlacklist"), policy.ClusterResourceBlacklist)
+		validateGroupKinds(pp.Child("namespaceResourceWhitelist"), policy.NamespaceResourceWhitelist)
+		validateGroupKinds(pp.Child("namespaceResourceBlacklist"), policy.NamespaceResourceBlacklist)
+	}
+	if c.ArgoCD.DefaultResourcePolicy != "" {
+		i := slices.IndexFunc(c.ArgoCD.ResourcePolicies, func(policy ResourcePolicyConfig) bool {
+			return policy.Name == c.ArgoCD.DefaultResourcePolicy
+		})
+		if i < 0 {
+			allErrs = append(allErrs, field.NotFound(p.Child("defaultResourcePolicy"), c.ArgoCD.DefaultResourcePolicy))
+		} else if c.ArgoCD.ResourcePolicies[i].TenantSelector != nil {
+			allErrs = append(allErrs, field.Invalid(p.Child("defaultResourcePolicy"), c.ArgoCD.DefaultResourcePolicy, "should not have tenantSelector"))
+		}
+	}
+	return allErrs
+}
+
+// OutputConfigMaps returns the ConfigMaps listing the namespaces of tenants.
+func (c *Config) OutputConfigMaps() []ConfigMapConfig {
+	if len(c.ArgoCD.ConfigMaps) == 0 {
+		return DefaultConfigMaps
+	}
+	return c.ArgoCD.ConfigMaps
+}
+
+// ConfigMapNamespace returns the namespace of the ConfigMap.
+func (c *Config) ConfigMapNamespace(cm ConfigMapConfig) string {
+	if cm.Namespace != "" {
+		return cm.Namespace
+	}
+	return c.ArgoCD.Namespace
+}
+
+// ConfigMapName returns the name of the ConfigMap for the application controller.
+// controllerName is ignored unless `perController` is true.
+func (cm ConfigMapConfig) ConfigMapName(controllerName string) (string, error) {
+	if !cm.PerController {
+		controllerName = ""
+	}
+	tpl, err := template.New("name").Option("missingkey=error").Parse(cm.Name)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := tpl.Execute(&buf, struct{ ControllerName string }{ControllerName: controllerName}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DataKey returns the key of the data of the ConfigMap.
+func (cm ConfigMapConfig) DataKey() string {
+	if cm.Key != "" {
+		return cm.Key
+	}
+	return DefaultConfigMapKey
+}
+
+// JoinNamespaces joins the namespaces with the separator of the ConfigMap.
+func (cm ConfigMapConfig) JoinNamespaces(namespaces []string) string {
+	if cm.Separator != "" {
+		return strings.Join(namespaces, cm.Separator)
+	}
+	return strings.Join(namespaces, DefaultConfigMapSeparator)
+}
+
+// ControllerNames returns the names of all application controllers including the default one.
+func (c *Config) ControllerNames() []string {
+	return append([]string{constants.DefaultApplicationControllerName}, c.ArgoCD.ApplicationControllers...)
+}
+
+// IsKnownController returns true if the application controller is managed by cattage.
+func (c *Config) IsKnownController(name string) bool {
+	if len(c.ArgoCD.ApplicationControllers) == 0 || name == "" {
+		return true
+	}
+	return name == constants.DefaultApplicationControllerName || slices.Contains(c.ArgoCD.ApplicationControllers, name)
+}
+
+// inCluster is the cluster where Argo CD runs, as registered by Argo CD by default.
+var inCluster = ClusterConfig{Name: "in-cluster", Server: "https://kubernetes.default.svc"}
+
+// AllowedCluster returns the cluster specified by its name or server URL if it can be a destination of tenants.
+// If both are specified, they must refer to the same cluster.
+// If no cluster is listed in the configuration, only the cluster where Argo CD runs is allowed.
+func (c *Config) AllowedCluster(name, server string) (ClusterConfig, bool) {
+	clusters := c.ArgoCD.Clusters
+	if len(clusters) == 0 {
+		clusters = []ClusterConfig{inCluster}
+	}
+	for _, cluster := range clusters {
+		if name != "" && cluster.Name != name {
+			continue
+		}
+		if server != "" && cluster.Server != server {
+			continue
+		}
+		if name == "" && server == "" {
+			continue
+		}
+		return cluster, true
+	}
+	return ClusterConfig{}, false
+}
+
+// IsAllowedRepository returns true if the tenant with the labels can use the repository.
+// The repository may be a glob pattern itself, in which case it must be matched by an allowed pattern as a string.
+func (c *Config) IsAllowedRepository(repo string, tenantLabels map[string]string) bool {
+	repos := c.ArgoCD.Repositories
+	if len(repos.AllowedPatterns) == 0 && len(repos.Rules) == 0 {
+		return true
+	}
+	match := func(patterns []string) bool {
+		return slices.ContainsFunc(patterns, func(pattern string) bool {
+			ok, _ := path.Match(pattern, repo)
+			return ok
+		})
+	}
+	if match(repos.AllowedPatterns) {
+		return true
+	}
+	for _, rule := range repos.Rules {
+		selector, err := metav1.LabelSelectorAsSelector(&rule.TenantSelector)
+		if err != nil {
+			continue
+		}
+		if selector.Matches(labels.Set(tenantLabels)) && match(rule.AllowedPatterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourcePolicy returns the resource policy of the name for the tenant with the labels.
+// If name is empty, the default resource policy is returned.
+// It returns an error if the policy does not exist or does not select the tenant.
+// It returns nil if neither name nor the default resource policy is specified.
+func (c *Config) ResourcePolicy(name string, tenantLabels map[string]string) (*ResourcePolicyConfig, error) {
+	if name == "" {
+		name = c.ArgoCD.DefaultResourcePolicy
+	}
+	if name == "" {
+		return nil, nil
+	}
+	i := slices.IndexFunc(c.ArgoCD.ResourcePolicies, func(policy ResourcePolicyConfig) bool {
+		return policy.Name == name
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("resource policy %s is not found", name)
+	}
+	policy := &c.ArgoCD.ResourcePolicies[i]
+	if policy.TenantSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(policy.TenantSelector)
+		if err != nil {
+			return nil, err
+		}
+		if !selector.Matches(labels.Set(tenantLabels)) {
+			return nil, fmt.Errorf("resource policy %s is not allowed for the tenant", name)
+		}
+	}
+	return policy, nil
+}
+
+// IsAllowedRole returns true if the role can be delegated to other tenants.
+func (c *Config) IsAllowedRole(role string) bool {
+	return len(c.Delegation.AllowedRoles) == 0 || slices.Contains(c.Delegation.AllowedRoles, role)
+}
+
+// DelegateGroups returns the groups that are given the read-only role of the delegated tenant by default.
+func (c *Config) DelegateGroups(tenantName string) ([]string, error) {
+	groups := make([]string, 0, len(c.ArgoCD.DelegateGroups))
+	for _, text := range c.ArgoCD.DelegateGroups {
+		group, err := renderDelegateGroup(text, tenantName)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func renderDelegateGroup(text, tenantName string) (string, error) {
+	tpl, err := template.New("group").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := tpl.Execute(&buf, struct{ Name string }{Name: tenantName}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 // Load loads configurations.
 func (c *Config) Load(data []byte) error {
 	return yaml.Unmarshal(data, c, yaml.DisallowUnknownFields)
@@ -3,8 +3,12 @@ package config
 import (
 	_ "embed"
 	"testing"
+	"time"
 
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
 	"github.com/google/go-cmp/cmp"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 //go:embed testdata/config.yaml
@@ -31,6 +35,20 @@ kind: RoleBinding
 ` {
 		t.Error("wrong rolebinding template:", cmp.Diff(c.Namespace.RoleBindingTemplate, `apiVersion: rbac.authorization.k8s.io/v1
 kind: RoleBinding
+`))
+	}
+	if c.Namespace.ResourceQuotaTemplate != `apiVersion: v1
+kind: ResourceQuota
+` {
+		t.Error("wrong resourcequota template:", cmp.Diff(c.Namespace.ResourceQuotaTemplate, `apiVersion: v1
+kind: ResourceQuota
+`))
+	}
+	if c.Namespace.LimitRangeTemplate != `apiVersion: v1
+kind: LimitRange
+` {
+		t.Error("wrong limitrange template:", cmp.Diff(c.Namespace.LimitRangeTemplate, `apiVersion: v1
+kind: LimitRange
 `))
 	}
 
@@ -53,6 +71,10 @@ kind: AppProject
 	t.Log(err)
 }
 
+const appProjectTemplate = `apiVersion: argoproj.io/v1alpha1
+kind: AppProject
+`
+
 func TestValidate(t *testing.T) {
 	testcases := []struct {
 		name    string
@@ -75,7 +97,7 @@ func TestValidate(t *testing.T) {
 				},
 				ArgoCD: ArgoCDConfig{
 					Namespace:          "argo",
-					AppProjectTemplate: "kind: AppProject",
+					AppProjectTemplate: appProjectTemplate,
 				},
 			},
 			isValid: true,
@@ -96,7 +118,7 @@ func TestValidate(t *testing.T) {
 				},
 				ArgoCD: ArgoCDConfig{
 					Namespace:          "argo",
-					AppProjectTemplate: "kind: AppProject",
+					AppProjectTemplate: appProjectTemplate,
 				},
 			},
 			isValid: false,
@@ -117,7 +139,7 @@ func TestValidate(t *testing.T) {
 				},
 				ArgoCD: ArgoCDConfig{
 					Namespace:          "argo",
-					AppProjectTemplate: "kind: AppProject",
+					AppProjectTemplate: appProjectTemplate,
 				},
 			},
 			isValid: false,
@@ -138,7 +160,7 @@ func TestValidate(t *testing.T) {
 				},
 				ArgoCD: ArgoCDConfig{
 					Namespace:          "argo",
-					AppProjectTemplate: "kind: AppProject",
+					AppProjectTemplate: appProjectTemplate,
 				},
 			},
 			isValid: false,
@@ -159,7 +181,7 @@ func TestValidate(t *testing.T) {
 				},
 				ArgoCD: ArgoCDConfig{
 					Namespace:          "invalid/argo",
-					AppProjectTemplate: "kind: AppProject",
+					AppProjectTemplate: appProjectTemplate,
 				},
 			},
 			isValid: false,
@@ -185,15 +207,1419 @@ func TestValidate(t *testing.T) {
 			},
 			isValid: false,
 		},
-	}
-
-	for _, testcase := range testcases {
-		err := testcase.config.Validate()
-		if testcase.isValid && err != nil {
-			t.Fatalf("%s: %s", testcase.name, err)
-		}
-		if !testcase.isValid && err == nil {
-			t.Fatalf("%s: invalid data are validated successfully", testcase.name)
-		}
+		{
+			name: "valid resource templates",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+					ResourceTemplates: []ResourceTemplate{
+						{Name: "deny-all", Template: "apiVersion: networking.k8s.io/v1\nkind: NetworkPolicy\nmetadata:\n  name: deny-all"},
+						{Name: "deployer", Template: "apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: deployer"},
+					},
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "duplicated resource template names",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+					ResourceTemplates: []ResourceTemplate{
+						{Name: "deny-all", Template: "kind: NetworkPolicy"},
+						{Name: "deny-all", Template: "kind: ServiceAccount"},
+					},
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "empty resource template",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+					ResourceTemplates: []ResourceTemplate{
+						{Name: "deny-all", Template: ""},
+					},
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid isolation allowed namespaces",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				Isolation: IsolationConfig{
+					Enabled:           true,
+					AllowedNamespaces: []string{"ingress/nginx"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid templates",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: `apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: admin
+subjects:
+  - kind: Group
+    name: {{ .Name }}
+  {{- range .Roles.admin }}
+  - kind: Group
+    name: {{ .Name }}
+  {{- end }}
+`,
+					ResourceQuotaTemplate: `spec:
+  hard:
+    pods: "100"
+`,
+					ResourceTemplates: []ResourceTemplate{
+						{Name: "deployer", Template: `{{- with .ExtraParams.Deployer }}
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{ . }}
+{{- end }}
+`},
+					},
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace: "argo",
+					AppProjectTemplate: `apiVersion: argoproj.io/v1alpha1
+kind: AppProject
+spec:
+  destinations:
+  {{- range .Namespaces }}
+  - namespace: {{ . }}
+    server: '*'
+  {{- end }}
+  syncWindows:
+  - kind: allow
+    schedule: '0 23 * * *'
+    duration: 1h
+`,
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "unparsable rolebinding template",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nname: {{ .Name }",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "rolebinding template that fails to execute",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nroleRef:\n  name: {{ .Unknown }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "rolebinding template with unknown field",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nsubject:\n- kind: Group\n  name: {{ .Name }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "rolebinding template with field of wrong type",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nsubjects: {{ .Name }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "rolebinding template with wrong kind",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: ClusterRoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid limitrange template",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+					LimitRangeTemplate:  "spec:\n  limits: foo",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "resource template without name",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+					ResourceTemplates: []ResourceTemplate{
+						{Name: "deployer", Template: "apiVersion: v1\nkind: ServiceAccount"},
+					},
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "appproject template without apiVersion",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: "kind: AppProject",
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "appproject template with invalid sync windows",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate + "spec:\n  syncWindows:\n    kind: allow\n",
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid application controllers and allowed roles",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second", "third"},
+				},
+				Delegation: DelegationConfig{
+					AllowedRoles: []string{"admin", "viewer"},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "invalid application controller name",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"Second"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate application controllers",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second", "second"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid label selector sharding",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second"},
+					Sharding: ShardingConfig{
+						Policy: ShardingPolicyLabelSelector,
+						Rules: []ShardingRule{
+							{
+								TenantSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "critical"}},
+								ControllerName: "second",
+							},
+						},
+					},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "valid balanced sharding",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second"},
+					Sharding: ShardingConfig{
+						Policy:    ShardingPolicyBalanced,
+						BalanceBy: BalanceByApplications,
+					},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "unknown sharding policy",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Sharding: ShardingConfig{
+						Policy: "RoundRobin",
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "label selector sharding without rules",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Sharding: ShardingConfig{
+						Policy: ShardingPolicyLabelSelector,
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "sharding rule with unknown controller",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second"},
+					Sharding: ShardingConfig{
+						Policy: ShardingPolicyLabelSelector,
+						Rules: []ShardingRule{
+							{
+								TenantSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "critical"}},
+								ControllerName: "third",
+							},
+						},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "sharding rule with invalid selector",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Sharding: ShardingConfig{
+						Policy: ShardingPolicyLabelSelector,
+						Rules: []ShardingRule{
+							{
+								TenantSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "not valid"}},
+								ControllerName: "default",
+							},
+						},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "balanced sharding without controllers",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Sharding: ShardingConfig{
+						Policy: ShardingPolicyBalanced,
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "unknown balance measure",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second"},
+					Sharding: ShardingConfig{
+						Policy:    ShardingPolicyBalanced,
+						BalanceBy: "Pods",
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "negative migration grace period",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Sharding: ShardingConfig{
+						MigrationGracePeriod: metav1.Duration{Duration: -time.Minute},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid configmaps",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second"},
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "{{ .ControllerName }}-application-controller-cm", PerController: true},
+						{Name: "{{ .ControllerName }}-applicationset-cm", PerController: true, Controllers: []string{"second"}},
+						{
+							Name:           "notifications-cm",
+							Namespace:      "notifications",
+							Key:            "notifications.namespaces",
+							Separator:      "\n",
+							TenantSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"notify": "true"}},
+						},
+					},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "configmap without name",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Key: "application.namespaces"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "unparsable configmap name template",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "{{ .ControllerName }-cm", PerController: true},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "configmap name template with unknown field",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "{{ .Unknown }}-cm"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid configmap name",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "Invalid_Name"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "configmap per controller without controller name",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "application-controller-cm", PerController: true},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate configmaps",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "{{ .ControllerName }}-cm", PerController: true},
+						{Name: "default-cm"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid configmap key",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "cm", Key: "invalid key"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid configmap namespace",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "cm", Namespace: "Invalid"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "configmap with invalid selector",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "cm", TenantSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"invalid key": "v"}}},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "configmap with unknown controller",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:              "argo",
+					AppProjectTemplate:     appProjectTemplate,
+					ApplicationControllers: []string{"second"},
+					ConfigMaps: []ConfigMapConfig{
+						{Name: "cm", Controllers: []string{"third"}},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid clusters",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Clusters: []ClusterConfig{
+						{Name: "in-cluster", Server: "https://kubernetes.default.svc"},
+						{Name: "remote"},
+						{Server: "https://remote.example.com"},
+					},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "cluster without name and server",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Clusters: []ClusterConfig{
+						{},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate cluster names",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Clusters: []ClusterConfig{
+						{Name: "remote"},
+						{Name: "remote", Server: "https://remote.example.com"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate cluster servers",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Clusters: []ClusterConfig{
+						{Server: "https://remote.example.com"},
+						{Name: "remote", Server: "https://remote.example.com"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid repositories",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Repositories: RepositoriesConfig{
+						AllowedPatterns: []string{"https://github.com/cybozu-go/*"},
+						Rules: []RepositoryRule{
+							{
+								TenantSelector:  metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+								AllowedPatterns: []string{"https://github.com/a-team/*"},
+							},
+						},
+						CredentialTemplate: `apiVersion: v1
+kind: Secret
+metadata:
+  labels:
+    argocd.argoproj.io/secret-type: repository
+stringData:
+  url: {{ .URL }}
+  username: {{ .Secret.username }}
+  password: {{ .Secret.password }}
+`,
+					},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "invalid repository pattern",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Repositories: RepositoriesConfig{
+						AllowedPatterns: []string{"https://github.com/cybozu-go/["},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "repository rule without patterns",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Repositories: RepositoriesConfig{
+						Rules: []RepositoryRule{
+							{TenantSelector: metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}},
+						},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid repository credential template",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					Repositories: RepositoriesConfig{
+						CredentialTemplate: "kind: ConfigMap",
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "valid resource policies",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ResourcePolicies: []ResourcePolicyConfig{
+						{
+							Name:                       "restricted",
+							NamespaceResourceBlacklist: []metav1.GroupKind{{Group: "", Kind: "ResourceQuota"}},
+						},
+						{
+							Name:                     "crd",
+							TenantSelector:           &metav1.LabelSelector{MatchLabels: map[string]string{"crd": "true"}},
+							ClusterResourceWhitelist: []metav1.GroupKind{{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}},
+						},
+					},
+					DefaultResourcePolicy: "restricted",
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "resource policy without kind",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ResourcePolicies: []ResourcePolicyConfig{
+						{Name: "restricted", ClusterResourceWhitelist: []metav1.GroupKind{{Group: "*"}}},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate resource policies",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ResourcePolicies: []ResourcePolicyConfig{
+						{Name: "restricted"},
+						{Name: "restricted"},
+					},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "unknown default resource policy",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:             "argo",
+					AppProjectTemplate:    appProjectTemplate,
+					DefaultResourcePolicy: "restricted",
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "default resource policy with tenant selector",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					ResourcePolicies: []ResourcePolicyConfig{
+						{Name: "crd", TenantSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"crd": "true"}}},
+					},
+					DefaultResourcePolicy: "crd",
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "empty allowed role",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				Delegation: DelegationConfig{
+					AllowedRoles: []string{"admin", ""},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate allowed roles",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				Delegation: DelegationConfig{
+					AllowedRoles: []string{"admin", "admin"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "strict templates referring to required and defaulted extra parameters",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nmetadata:\n  labels:\n    team: {{ .ExtraParams.team }}\n    env: {{ .ExtraParams.env }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					Schema:          extraParamsSchema(t),
+					StrictTemplates: true,
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "strict templates referring to optional extra parameters",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nmetadata:\n  labels:\n    cpu: {{ .ExtraParams.cpu }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					Schema:          extraParamsSchema(t),
+					StrictTemplates: true,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "templates referring to optional extra parameters without the strict mode",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nmetadata:\n  labels:\n    cpu: {{ .ExtraParams.cpu }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					Schema: extraParamsSchema(t),
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "templates referring to roles without the strict mode",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nsubjects:\n{{- range .Roles.admin }}\n- kind: Group\n  name: {{ .Name }}\n{{- end }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					Schema: extraParamsSchema(t),
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "strict templates referring to roles without index",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding\nsubjects:\n{{- range .Roles.admin }}\n- kind: Group\n  name: {{ .Name }}\n{{- end }}",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					StrictTemplates: true,
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "extra parameters schema not describing an object",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					Schema: &apiextensionsv1.JSONSchemaProps{Type: "string"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "delegate groups",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					DelegateGroups:     []string{"cybozu-go:{{ .Name }}"},
+				},
+			},
+			isValid: true,
+		},
+		{
+			name: "invalid delegate groups",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					DelegateGroups:     []string{"cybozu-go:{{ .Team }}"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "empty delegate groups",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+					DelegateGroups:     []string{"{{ if false }}{{ .Name }}{{ end }}"},
+				},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid default of extra parameters",
+			config: &Config{
+				Namespace: NamespaceConfig{
+					RoleBindingTemplate: "kind: RoleBinding",
+				},
+				ArgoCD: ArgoCDConfig{
+					Namespace:          "argo",
+					AppProjectTemplate: appProjectTemplate,
+				},
+				ExtraParams: ExtraParamsConfig{
+					Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"env": {Type: "string", Enum: []apiextensionsv1.JSON{{Raw: []byte(`"prod"`)}}, Default: &apiextensionsv1.JSON{Raw: []byte(`"dev"`)}},
+						},
+					},
+				},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		err := testcase.config.Validate()
+		if testcase.isValid && err != nil {
+			t.Fatalf("%s: %s", testcase.name, err)
+		}
+		if !testcase.isValid && err == nil {
+			t.Fatalf("%s: invalid data are validated successfully", testcase.name)
+		}
+	}
+}
+
+func TestIsKnownController(t *testing.T) {
+	c := &Config{}
+	if !c.IsKnownController("any") {
+		t.Error("any controller should be known when applicationControllers is empty")
+	}
+
+	c.ArgoCD.ApplicationControllers = []string{"second"}
+	for _, name := range []string{"", "default", "second"} {
+		if !c.IsKnownController(name) {
+			t.Errorf("%q should be known", name)
+		}
+	}
+	if c.IsKnownController("third") {
+		t.Error("third should not be known")
+	}
+}
+
+func TestConfigMapName(t *testing.T) {
+	testcases := []struct {
+		name     string
+		cm       ConfigMapConfig
+		expected string
+	}{
+		{
+			name:     "per controller",
+			cm:       ConfigMapConfig{Name: "{{ .ControllerName }}-application-controller-cm", PerController: true},
+			expected: "second-application-controller-cm",
+		},
+		{
+			name:     "not per controller",
+			cm:       ConfigMapConfig{Name: "all{{ .ControllerName }}-cm"},
+			expected: "all-cm",
+		},
+	}
+
+	for _, tc := range testcases {
+		name, err := tc.cm.ConfigMapName("second")
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if name != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, name)
+		}
+	}
+}
+
+func TestJoinNamespaces(t *testing.T) {
+	namespaces := []string{"app-a", "sub-1"}
+	if joined := (ConfigMapConfig{}).JoinNamespaces(namespaces); joined != "app-a,sub-1" {
+		t.Errorf("unexpected joined namespaces with the default separator: %q", joined)
+	}
+	if joined := (ConfigMapConfig{Separator: "\n"}).JoinNamespaces(namespaces); joined != "app-a\nsub-1" {
+		t.Errorf("unexpected joined namespaces: %q", joined)
+	}
+}
+
+func TestAllowedCluster(t *testing.T) {
+	c := &Config{}
+	if _, ok := c.AllowedCluster("any", ""); ok {
+		t.Error("an unlisted cluster should not be allowed when clusters is empty")
+	}
+	if _, ok := c.AllowedCluster("", "https://any.example.com"); ok {
+		t.Error("an unlisted server should not be allowed when clusters is empty")
+	}
+	if cluster, ok := c.AllowedCluster("in-cluster", ""); !ok || cluster != inCluster {
+		t.Errorf("the in-cluster should be allowed when clusters is empty: %v", cluster)
+	}
+	if cluster, ok := c.AllowedCluster("", "https://kubernetes.default.svc"); !ok || cluster != inCluster {
+		t.Errorf("the in-cluster should be allowed by its server when clusters is empty: %v", cluster)
+	}
+	if _, ok := c.AllowedCluster("", ""); ok {
+		t.Error("a cluster without name and server should not be allowed")
+	}
+
+	c.ArgoCD.Clusters = []ClusterConfig{
+		{Name: "in-cluster", Server: "https://kubernetes.default.svc"},
+		{Name: "remote"},
+	}
+	testcases := []struct {
+		name     string
+		server   string
+		expected ClusterConfig
+		allowed  bool
+	}{
+		{name: "in-cluster", expected: c.ArgoCD.Clusters[0], allowed: true},
+		{server: "https://kubernetes.default.svc", expected: c.ArgoCD.Clusters[0], allowed: true},
+		{name: "in-cluster", server: "https://kubernetes.default.svc", expected: c.ArgoCD.Clusters[0], allowed: true},
+		{name: "remote", expected: c.ArgoCD.Clusters[1], allowed: true},
+		{name: "remote", server: "https://kubernetes.default.svc"},
+		{name: "unknown"},
+		{server: "https://unknown.example.com"},
+	}
+	for _, tc := range testcases {
+		cluster, ok := c.AllowedCluster(tc.name, tc.server)
+		if ok != tc.allowed {
+			t.Errorf("name=%q server=%q: expected allowed=%v", tc.name, tc.server, tc.allowed)
+			continue
+		}
+		if cluster != tc.expected {
+			t.Errorf("name=%q server=%q: unexpected cluster %v", tc.name, tc.server, cluster)
+		}
+	}
+}
+
+func TestDelegateGroups(t *testing.T) {
+	c := &Config{}
+	groups, err := c.DelegateGroups("a-team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("no group should be returned when delegateGroups is empty: %v", groups)
+	}
+
+	c.ArgoCD.DelegateGroups = []string{"cybozu-go:{{ .Name }}", "cybozu-go:{{ .Name }}-viewers"}
+	groups, err = c.DelegateGroups("a-team")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"cybozu-go:a-team", "cybozu-go:a-team-viewers"}, groups); diff != "" {
+		t.Errorf("unexpected groups: %s", diff)
+	}
+}
+
+func TestIsAllowedRepository(t *testing.T) {
+	c := &Config{}
+	if !c.IsAllowedRepository("https://example.com/any.git", nil) {
+		t.Error("any repository should be allowed when no pattern is configured")
+	}
+
+	c.ArgoCD.Repositories = RepositoriesConfig{
+		AllowedPatterns: []string{"https://github.com/cybozu-go/*"},
+		Rules: []RepositoryRule{
+			{
+				TenantSelector:  metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}},
+				AllowedPatterns: []string{"https://github.com/a-team/*"},
+			},
+		},
+	}
+	testcases := []struct {
+		repo    string
+		labels  map[string]string
+		allowed bool
+	}{
+		{repo: "https://github.com/cybozu-go/cattage.git", allowed: true},
+		{repo: "https://github.com/cybozu-go/*", allowed: true},
+		{repo: "https://github.com/cybozu-go/a/b.git"},
+		{repo: "*"},
+		{repo: "https://github.com/a-team/app.git"},
+		{repo: "https://github.com/a-team/app.git", labels: map[string]string{"team": "b"}},
+		{repo: "https://github.com/a-team/app.git", labels: map[string]string{"team": "a"}, allowed: true},
+		{repo: "https://github.com/cybozu-go/cattage.git", labels: map[string]string{"team": "a"}, allowed: true},
+	}
+	for _, tc := range testcases {
+		if allowed := c.IsAllowedRepository(tc.repo, tc.labels); allowed != tc.allowed {
+			t.Errorf("repo=%q labels=%v: expected allowed=%v", tc.repo, tc.labels, tc.allowed)
+		}
+	}
+}
+
+func TestResourcePolicy(t *testing.T) {
+	c := &Config{}
+	if policy, err := c.ResourcePolicy("", nil); err != nil || policy != nil {
+		t.Errorf("no resource policy should be returned: %v, %v", policy, err)
+	}
+
+	c.ArgoCD.ResourcePolicies = []ResourcePolicyConfig{
+		{Name: "restricted"},
+		{Name: "crd", TenantSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"crd": "true"}}},
+	}
+	c.ArgoCD.DefaultResourcePolicy = "restricted"
+	testcases := []struct {
+		name     string
+		labels   map[string]string
+		expected string
+	}{
+		{expected: "restricted"},
+		{name: "restricted", expected: "restricted"},
+		{name: "crd", labels: map[string]string{"crd": "true"}, expected: "crd"},
+		{name: "crd"},
+		{name: "unknown"},
+	}
+	for _, tc := range testcases {
+		policy, err := c.ResourcePolicy(tc.name, tc.labels)
+		if tc.expected == "" {
+			if err == nil {
+				t.Errorf("name=%q labels=%v: expected an error", tc.name, tc.labels)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("name=%q labels=%v: unexpected error: %v", tc.name, tc.labels, err)
+			continue
+		}
+		if policy.Name != tc.expected {
+			t.Errorf("name=%q labels=%v: unexpected policy %s", tc.name, tc.labels, policy.Name)
+		}
+	}
+}
+
+const extraParamsSchemaYAML = `extraParams:
+  schema:
+    type: object
+    required: [team]
+    properties:
+      team:
+        type: string
+      env:
+        type: string
+        enum: [dev, prod]
+        default: dev
+      cpu:
+        type: integer
+        minimum: 1
+      alerts:
+        type: object
+        properties:
+          enabled:
+            type: boolean
+            default: true
+`
+
+func extraParamsSchema(t *testing.T) *apiextensionsv1.JSONSchemaProps {
+	c := &Config{}
+	if err := c.Load([]byte(extraParamsSchemaYAML)); err != nil {
+		t.Fatal(err)
+	}
+	return c.ExtraParams.Schema
+}
+
+func TestTemplateOptions(t *testing.T) {
+	c := &Config{ExtraParams: ExtraParamsConfig{Schema: extraParamsSchema(t)}}
+	if len(c.TemplateOptions()) != 0 {
+		t.Error("templates should not be strict without strictTemplates")
+	}
+
+	c.ExtraParams.StrictTemplates = true
+	if diff := cmp.Diff([]string{"missingkey=error"}, c.TemplateOptions()); diff != "" {
+		t.Errorf("templates should be strict with strictTemplates: %s", diff)
+	}
+}
+
+func TestValidateTenantTemplates(t *testing.T) {
+	c := &Config{}
+	testcases := []struct {
+		name    string
+		spec    cattagev1beta1.TenantSpec
+		isValid bool
+	}{
+		{name: "no templates", isValid: true},
+		{name: "valid templates", spec: cattagev1beta1.TenantSpec{ResourceQuotaTemplate: "spec:\n  hard:\n    cpu: 1", LimitRangeTemplate: "kind: LimitRange"}, isValid: true},
+		{name: "unclosed action", spec: cattagev1beta1.TenantSpec{ResourceQuotaTemplate: "spec:\n  hard: {{ .Name"}},
+		{name: "wrong kind", spec: cattagev1beta1.TenantSpec{ResourceQuotaTemplate: "kind: LimitRange"}},
+		{name: "invalid limits", spec: cattagev1beta1.TenantSpec{LimitRangeTemplate: "spec:\n  limits: 1"}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			allErrs := c.ValidateTenantTemplates(&tc.spec)
+			if tc.isValid && len(allErrs) != 0 {
+				t.Errorf("should be valid: %v", allErrs.ToAggregate())
+			}
+			if !tc.isValid && len(allErrs) == 0 {
+				t.Error("should be invalid")
+			}
+		})
+	}
+}
+
+func TestExtraParams(t *testing.T) {
+	c := &Config{}
+	params := map[string]interface{}{"team": "a"}
+	if diff := cmp.Diff(params, c.DefaultExtraParams(params)); diff != "" {
+		t.Errorf("extra parameters should not be changed without a schema: %s", diff)
+	}
+	if err := c.ValidateExtraParams(nil); err != nil {
+		t.Errorf("any extra parameters should be valid without a schema: %v", err)
+	}
+
+	c.ExtraParams.Schema = extraParamsSchema(t)
+	testcases := []struct {
+		name     string
+		params   map[string]interface{}
+		expected map[string]interface{}
+		isValid  bool
+	}{
+		{
+			name:     "defaults",
+			params:   map[string]interface{}{"team": "a", "alerts": map[string]interface{}{}},
+			expected: map[string]interface{}{"team": "a", "env": "dev", "alerts": map[string]interface{}{"enabled": true}},
+			isValid:  true,
+		},
+		{
+			name:     "specified values",
+			params:   map[string]interface{}{"team": "a", "env": "prod", "cpu": float64(2)},
+			expected: map[string]interface{}{"team": "a", "env": "prod", "cpu": float64(2)},
+			isValid:  true,
+		},
+		{
+			name:     "missing required parameter",
+			expected: map[string]interface{}{"env": "dev"},
+		},
+		{
+			name:     "wrong type",
+			params:   map[string]interface{}{"team": "a", "cpu": "2"},
+			expected: map[string]interface{}{"team": "a", "env": "dev", "cpu": "2"},
+		},
+		{
+			name:     "not in enum",
+			params:   map[string]interface{}{"team": "a", "env": "stage"},
+			expected: map[string]interface{}{"team": "a", "env": "stage"},
+		},
+	}
+	for _, tc := range testcases {
+		params := c.DefaultExtraParams(tc.params)
+		if diff := cmp.Diff(tc.expected, params); diff != "" {
+			t.Errorf("%s: unexpected defaults: %s", tc.name, diff)
+		}
+		err := c.ValidateExtraParams(params)
+		if tc.isValid && err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !tc.isValid && err == nil {
+			t.Errorf("%s: invalid extra parameters are validated successfully", tc.name)
+		}
+	}
+
+	params = map[string]interface{}{"team": "a"}
+	c.DefaultExtraParams(params)
+	if len(params) != 1 {
+		t.Errorf("extra parameters should not be modified: %v", params)
 	}
 }
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cybozu-go/cattage/internal/metrics"
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Watcher reloads the configuration file when it is changed.
+// Invalid configurations are rejected and the current ones are kept.
+type Watcher struct {
+	path   string
+	holder *Holder
+}
+
+// NewWatcher returns a Watcher that reloads the configuration file at path into holder.
+func NewWatcher(path string, holder *Holder) *Watcher {
+	return &Watcher{
+		path:   path,
+		holder: holder,
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable.
+// The configurations are reloaded on all replicas because the webhooks also use them.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// Start implements manager.Runnable.
+func (w *Watcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("config-watcher")
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	// Watch the directory instead of the file because a ConfigMap volume replaces the file with a symbolic link.
+	if err := watcher.Add(filepath.Dir(w.path)); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.path, err)
+	}
+	metrics.ConfigLastReloadSuccessful.Set(1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !ev.Has(fsnotify.Create) && !ev.Has(fsnotify.Write) && !ev.Has(fsnotify.Rename) && !ev.Has(fsnotify.Remove) {
+				continue
+			}
+			if err := w.reload(logger); err != nil {
+				metrics.ConfigReloadFailuresTotal.Inc()
+				metrics.ConfigLastReloadSuccessful.Set(0)
+				logger.Error(err, "failed to reload the configuration file; keeping the current configurations", "path", w.path)
+				continue
+			}
+			metrics.ConfigLastReloadSuccessful.Set(1)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			logger.Error(err, "error while watching the configuration file", "path", w.path)
+		}
+	}
+}
+
+func (w *Watcher) reload(logger logr.Logger) error {
+	data, err := os.ReadFile(w.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// The file is being replaced. It will be reloaded on the next event.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", w.path, err)
+	}
+	cfg := &Config{}
+	if err := cfg.Load(data); err != nil {
+		return fmt.Errorf("unable to load the configuration file: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configurations: %w", err)
+	}
+	if equality.Semantic.DeepEqual(cfg, w.holder.Get()) {
+		return nil
+	}
+	w.holder.Set(cfg)
+	logger.Info("reloaded the configuration file", "path", w.path)
+	return nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cybozu-go/cattage/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+const watcherTestConfig = `namespace:
+  commonLabels:
+    foo: %s
+  roleBindingTemplate: |
+    apiVersion: rbac.authorization.k8s.io/v1
+    kind: RoleBinding
+argocd:
+  namespace: argocd
+  appProjectTemplate: |
+    apiVersion: argoproj.io/v1alpha1
+    kind: AppProject
+`
+
+func writeConfig(t *testing.T, path, data string) {
+	t.Helper()
+	// Write to a temporary file and rename it to replace the file atomically.
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, fmt.Sprintf(watcherTestConfig, "bar"))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &Config{}
+	if err := cfg.Load(data); err != nil {
+		t.Fatal(err)
+	}
+	holder := NewHolder(cfg)
+	changed := make(chan *Config, 10)
+	holder.OnChange(func(c *Config) {
+		changed <- c
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		done <- NewWatcher(path, holder).Start(ctx)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	writeConfig(t, path, fmt.Sprintf(watcherTestConfig, "baz"))
+	select {
+	case c := <-changed:
+		if c.Namespace.CommonLabels["foo"] != "baz" {
+			t.Error("wrong common labels:", c.Namespace.CommonLabels)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("configurations are not reloaded")
+	}
+	if holder.Get().Namespace.CommonLabels["foo"] != "baz" {
+		t.Error("holder does not have the new configurations:", holder.Get().Namespace.CommonLabels)
+	}
+
+	failures := testutil.ToFloat64(metrics.ConfigReloadFailuresTotal)
+	writeConfig(t, path, fmt.Sprintf(watcherTestConfig, "invalid!"))
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(metrics.ConfigReloadFailuresTotal) == failures {
+		if time.Now().After(deadline) {
+			t.Fatal("failure of reload is not reported")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if testutil.ToFloat64(metrics.ConfigLastReloadSuccessful) != 0 {
+		t.Error("last reload is reported as successful")
+	}
+	if holder.Get().Namespace.CommonLabels["foo"] != "baz" {
+		t.Error("invalid configurations are applied:", holder.Get().Namespace.CommonLabels)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -3,3 +3,4 @@ package constants
 const RootNamespaceIndex = "cattage.namespaces.root"
 const TenantNamespaceIndex = "cattage.namespaces.tenant"
 const ControllerNameIndex = "cattage.tenants.controller"
+const ParentTenantIndex = "cattage.tenants.parent"
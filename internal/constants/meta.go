@@ -10,6 +10,12 @@ const OwnerTenant = MetaPrefix + "tenant"
 
 const OwnerAppNamespace = MetaPrefix + "owner-namespace"
 
+const ResourceTemplateLabel = MetaPrefix + "resource-template"
+
+const ResourcesAnnotation = MetaPrefix + "resources"
+
+const IsolationNetworkPolicyName = "cattage-tenant-isolation"
+
 const TenantFieldManager = MetaPrefix + "tenant-controller"
 
 const DefaultApplicationControllerName = "default"
@@ -17,3 +23,10 @@ const DefaultApplicationControllerName = "default"
 const ManagedByLabel = "app.kubernetes.io/managed-by"
 const PartOfLabel = "app.kubernetes.io/part-of"
 const ControllerNameLabel = MetaPrefix + "controller-name"
+
+// MigrationAckAnnotation is the annotation on a tenant to finish the migration between application controllers without waiting for the grace period.
+// The value must be the name of the new controller.
+const MigrationAckAnnotation = MetaPrefix + "migration-ack"
+
+// DelegateRolePrefix is the prefix of the read-only roles of delegated tenants in AppProjects.
+const DelegateRolePrefix = "delegate-"
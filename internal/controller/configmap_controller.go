@@ -0,0 +1,308 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// configMapLabels are the labels of the ConfigMaps listing the namespaces of tenants.
+var configMapLabels = map[string]string{
+	constants.ManagedByLabel: "cattage",
+	constants.PartOfLabel:    "argocd",
+}
+
+// configMapsRequest is the only request of ConfigMapReconciler.
+// All ConfigMaps are reconciled at once because their contents depend on each other.
+var configMapsRequest = reconcile.Request{NamespacedName: types.NamespacedName{Name: "application-controller-configmaps"}}
+
+func NewConfigMapReconciler(client client.Client, config *config.Holder) *ConfigMapReconciler {
+	return &ConfigMapReconciler{
+		client: client,
+		config: config,
+	}
+}
+
+// ConfigMapReconciler reconciles the ConfigMaps listing the namespaces for application-controllers.
+type ConfigMapReconciler struct {
+	client client.Client
+	config *config.Holder
+}
+
+// tenantNamespaces is a tenant and the namespaces belonging to it.
+type tenantNamespaces struct {
+	tenant     cattagev1beta1.Tenant
+	namespaces []string
+}
+
+// listTenantNamespaces lists the namespaces of the tenants sorted by the names of the tenants.
+// The namespaces are compacted into the namespace patterns of the tenants if enabled in the configuration.
+func listTenantNamespaces(ctx context.Context, c client.Client, cfg *config.Config, tenants []cattagev1beta1.Tenant) ([]tenantNamespaces, error) {
+	// All namespaces are listed at once instead of listing the namespaces for each tenant.
+	nss := &corev1.NamespaceList{}
+	if err := c.List(ctx, nss); err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	owned := map[string][]string{}
+	for _, ns := range nss.Items {
+		if owner := ns.Labels[constants.OwnerTenant]; owner != "" {
+			owned[owner] = append(owned[owner], ns.Name)
+		}
+	}
+
+	result := make([]tenantNamespaces, len(tenants))
+	for i, t := range tenants {
+		namespaces := owned[t.Name]
+		if cfg.ArgoCD.CompactNamespaces {
+			namespaces = compactNamespaces(&t, namespaces, nss.Items)
+		}
+		result[i] = tenantNamespaces{tenant: t, namespaces: namespaces}
+	}
+	slices.SortFunc(result, func(x, y tenantNamespaces) int {
+		return cmp.Compare(x.tenant.Name, y.tenant.Name)
+	})
+	return result, nil
+}
+
+// Reconcile creates or updates the ConfigMaps listing the namespaces of tenants as specified in the configuration,
+// and deletes the ConfigMaps that are no longer generated.
+func (r *ConfigMapReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	tenantList := &cattagev1beta1.TenantList{}
+	if err := r.client.List(ctx, tenantList); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	cfg := r.config.Get()
+	tenants, err := listTenantNamespaces(ctx, r.client, cfg, slices.DeleteFunc(tenantList.Items, func(t cattagev1beta1.Tenant) bool {
+		return t.DeletionTimestamp != nil
+	}))
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	desired := map[types.NamespacedName]struct{}{}
+	for _, out := range cfg.OutputConfigMaps() {
+		cms, err := renderOutputConfigMaps(cfg, out, tenants)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		for _, cm := range cms {
+			if err := r.applyConfigMap(ctx, cm.configMap, cm.tenants); err != nil {
+				return ctrl.Result{}, err
+			}
+			desired[client.ObjectKeyFromObject(cm.configMap)] = struct{}{}
+		}
+	}
+
+	// Delete the ConfigMaps that are no longer generated, such as the ones of the controllers that no tenant is assigned to
+	// and the ones left in the previous namespace.
+	cmList := &corev1.ConfigMapList{}
+	if err := r.client.List(ctx, cmList, client.MatchingLabels(configMapLabels)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list configmaps: %w", err)
+	}
+	for _, cm := range cmList.Items {
+		if _, ok := desired[client.ObjectKeyFromObject(&cm)]; ok {
+			continue
+		}
+		if err := r.client.Delete(ctx, &cm); client.IgnoreNotFound(err) != nil {
+			logger.Error(err, "failed to delete ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
+			return ctrl.Result{}, err
+		}
+		logger.Info("deleted orphaned ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// applyConfigMap creates or updates the ConfigMap owned by the tenants.
+func (r *ConfigMapReconciler) applyConfigMap(ctx context.Context, desired *corev1.ConfigMap, tenants []tenantNamespaces) error {
+	logger := log.FromContext(ctx)
+
+	cm := &corev1.ConfigMap{}
+	cm.Name = desired.Name
+	cm.Namespace = desired.Namespace
+	op, err := ctrl.CreateOrUpdate(ctx, r.client, cm, func() error {
+		cm.Labels = desired.Labels
+		cm.Data = desired.Data
+		cm.OwnerReferences = nil
+		for _, t := range tenants {
+			err := controllerutil.SetOwnerReference(&t.tenant, cm, r.client.Scheme())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logger.Error(err, "failed to update ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
+		return err
+	}
+	if op != controllerutil.OperationResultNone {
+		tenantNames := make([]string, len(tenants))
+		for i, t := range tenants {
+			tenantNames[i] = t.tenant.Name
+		}
+		logger.Info("ConfigMap successfully reconciled", "namespace", cm.Namespace, "name", cm.Name, "data", cm.Data, "tenants", tenantNames)
+	}
+	return nil
+}
+
+// renderedConfigMap is a ConfigMap and the tenants listed in it.
+type renderedConfigMap struct {
+	configMap *corev1.ConfigMap
+	tenants   []tenantNamespaces
+}
+
+// renderOutputConfigMaps renders the ConfigMaps listing the namespaces of the tenants selected by out.
+// A ConfigMap is rendered for each application-controller if `perController` is true.
+func renderOutputConfigMaps(cfg *config.Config, out config.ConfigMapConfig, tenants []tenantNamespaces) ([]renderedConfigMap, error) {
+	groups := map[string][]tenantNamespaces{}
+	for _, t := range tenants {
+		ok, err := outputSelects(out, &t.tenant)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		controllerName := ""
+		if out.PerController {
+			controllerName = tenantControllerName(&t.tenant)
+		}
+		groups[controllerName] = append(groups[controllerName], t)
+	}
+	if !out.PerController && len(groups) == 0 {
+		// The ConfigMap is generated even if no tenant is listed.
+		groups[""] = nil
+	}
+
+	result := make([]renderedConfigMap, 0, len(groups))
+	for _, controllerName := range slices.Sorted(maps.Keys(groups)) {
+		name, err := out.ConfigMapName(controllerName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render the name of ConfigMap %s: %w", out.Name, err)
+		}
+		namespaces := make([]string, 0)
+		for _, t := range groups[controllerName] {
+			namespaces = append(namespaces, t.namespaces...)
+		}
+		slices.Sort(namespaces)
+		namespaces = slices.Compact(namespaces)
+
+		cm := &corev1.ConfigMap{}
+		cm.APIVersion = "v1"
+		cm.Kind = "ConfigMap"
+		cm.Name = name
+		cm.Namespace = cfg.ConfigMapNamespace(out)
+		cm.Labels = maps.Clone(configMapLabels)
+		if out.PerController {
+			cm.Labels[constants.ControllerNameLabel] = controllerName
+		}
+		cm.Data = map[string]string{
+			out.DataKey(): out.JoinNamespaces(namespaces),
+		}
+		result = append(result, renderedConfigMap{configMap: cm, tenants: groups[controllerName]})
+	}
+	return result, nil
+}
+
+// tenantConfigMap returns the reference to the first ConfigMap for the application-controller that lists the namespaces of the tenant.
+// It returns nil if no such ConfigMap is generated, for example while the tenant is migrating between controllers.
+func tenantConfigMap(cfg *config.Config, tenant *cattagev1beta1.Tenant) (*cattagev1beta1.ObjectReference, error) {
+	for _, out := range cfg.OutputConfigMaps() {
+		if !out.PerController {
+			continue
+		}
+		ok, err := outputSelects(out, tenant)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		name, err := out.ConfigMapName(tenantControllerName(tenant))
+		if err != nil {
+			return nil, err
+		}
+		return &cattagev1beta1.ObjectReference{
+			Namespace: cfg.ConfigMapNamespace(out),
+			Name:      name,
+		}, nil
+	}
+	return nil, nil
+}
+
+// outputSelects returns true if the tenant is listed in the ConfigMap.
+// Tenants migrating between controllers are not listed if the ConfigMap depends on the controllers.
+func outputSelects(out config.ConfigMapConfig, tenant *cattagev1beta1.Tenant) (bool, error) {
+	if tenant.Status.Migration != nil && (out.PerController || len(out.Controllers) != 0) {
+		return false, nil
+	}
+	if len(out.Controllers) != 0 && !slices.Contains(out.Controllers, tenantControllerName(tenant)) {
+		return false, nil
+	}
+	if out.TenantSelector == nil {
+		return true, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(out.TenantSelector)
+	if err != nil {
+		return false, err
+	}
+	return selector.Matches(labels.Set(tenant.Labels)), nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConfigMapReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	enqueue := func(ctx context.Context, o client.Object) []reconcile.Request {
+		return []reconcile.Request{configMapsRequest}
+	}
+	// Namespaces affect the ConfigMaps only when they belong to tenants, or may match the namespace patterns of tenants.
+	// An update is mapped from both the old and new objects, so removing the label is also handled.
+	nsHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		if o.GetLabels()[constants.OwnerTenant] == "" && !r.config.Get().ArgoCD.CompactNamespaces {
+			return nil
+		}
+		return []reconcile.Request{configMapsRequest}
+	}
+	cmHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		if !labels.SelectorFromSet(configMapLabels).Matches(labels.Set(o.GetLabels())) {
+			return nil
+		}
+		return []reconcile.Request{configMapsRequest}
+	}
+
+	reloaded := make(chan event.GenericEvent, 1)
+	r.config.OnChange(func(*config.Config) {
+		select {
+		case reloaded <- event.GenericEvent{Object: &corev1.ConfigMap{}}:
+		default:
+		}
+	})
+
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("configmap").
+		Watches(&cattagev1beta1.Tenant{}, handler.EnqueueRequestsFromMapFunc(enqueue)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(nsHandler)).
+		Watches(&corev1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(cmHandler)).
+		WatchesRawSource(source.Channel(reloaded, handler.EnqueueRequestsFromMapFunc(enqueue))).
+		Complete(r)
+}
@@ -0,0 +1,159 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// parentRole is the role that the ancestors of a tenant are delegated.
+const parentRole = "admin"
+
+// ancestors returns the ancestors of the tenant from the parent to the root.
+func (r *TenantReconciler) ancestors(ctx context.Context, tenant *cattagev1beta1.Tenant) ([]cattagev1beta1.Tenant, error) {
+	result := make([]cattagev1beta1.Tenant, 0)
+	visited := []string{tenant.Name}
+	name := tenant.Spec.Parent
+	for name != "" {
+		if slices.Contains(visited, name) {
+			return nil, withReason(cattagev1beta1.ReasonCyclicHierarchy, fmt.Errorf("tenant hierarchy has a cycle: %v", append(visited, name)))
+		}
+		visited = append(visited, name)
+
+		parent := cattagev1beta1.Tenant{}
+		err := r.client.Get(ctx, client.ObjectKey{Name: name}, &parent)
+		if apierrors.IsNotFound(err) {
+			return nil, withReason(cattagev1beta1.ReasonParentNotFound, fmt.Errorf("parent tenant %s is not found: %w", name, err))
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, parent)
+		name = parent.Spec.Parent
+	}
+	return result, nil
+}
+
+func ancestorNames(ancestors []cattagev1beta1.Tenant) []string {
+	names := make([]string, len(ancestors))
+	for i, a := range ancestors {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// resolveTenant returns a copy of the tenant whose spec includes what is inherited from the ancestors.
+//   - Repositories are the union of those of the tenant and the ancestors.
+//   - ExtraParams of the ancestors are used as defaults. The nearer tenant takes precedence.
+//   - Delegates of the ancestors are also delegated access to the tenant, and the ancestors are delegated the admin role.
+//
+// The defaults in the schema of the configuration are applied to ExtraParams after they are inherited.
+func (r *TenantReconciler) resolveTenant(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) (*cattagev1beta1.Tenant, error) {
+	resolved := tenant.DeepCopy()
+	if tenant.Spec.Parent == "" {
+		return r.resolveExtraParams(cfg, resolved)
+	}
+
+	ancestors, err := r.ancestors(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	params := maps.Clone(tenant.Spec.ExtraParams.ToMap())
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	delegates := slices.Clone(tenant.Spec.Delegates)
+	for _, a := range ancestors {
+		resolved.Spec.ArgoCD.Repositories = append(resolved.Spec.ArgoCD.Repositories, a.Spec.ArgoCD.Repositories...)
+		for k, v := range a.Spec.ExtraParams.ToMap() {
+			if _, ok := params[k]; !ok {
+				params[k] = v
+			}
+		}
+		delegates = append(delegates, a.Spec.Delegates...)
+		delegates = append(delegates, cattagev1beta1.DelegateSpec{
+			Name:  a.Name,
+			Roles: []string{parentRole},
+		})
+	}
+
+	slices.Sort(resolved.Spec.ArgoCD.Repositories)
+	resolved.Spec.ArgoCD.Repositories = slices.Compact(resolved.Spec.ArgoCD.Repositories)
+	if len(params) != 0 {
+		resolved.Spec.ExtraParams = &cattagev1beta1.Params{Data: params}
+	}
+	resolved.Spec.Delegates = mergeDelegates(tenant.Name, delegates)
+	return r.resolveExtraParams(cfg, resolved)
+}
+
+// resolveExtraParams applies the defaults in the schema to ExtraParams of the resolved tenant and validates them.
+func (r *TenantReconciler) resolveExtraParams(cfg *config.Config, resolved *cattagev1beta1.Tenant) (*cattagev1beta1.Tenant, error) {
+	if cfg.ExtraParams.Schema == nil {
+		return resolved, nil
+	}
+	params := cfg.DefaultExtraParams(resolved.Spec.ExtraParams.ToMap())
+	if err := cfg.ValidateExtraParams(params); err != nil {
+		return nil, withReason(cattagev1beta1.ReasonInvalidExtraParams, fmt.Errorf("invalid extra parameters: %w", err))
+	}
+	if len(params) != 0 {
+		resolved.Spec.ExtraParams = &cattagev1beta1.Params{Data: params}
+	}
+	return resolved, nil
+}
+
+// mergeDelegates merges the roles and the groups of the same delegated tenants, excluding the tenant itself.
+func mergeDelegates(self string, delegates []cattagev1beta1.DelegateSpec) []cattagev1beta1.DelegateSpec {
+	result := make([]cattagev1beta1.DelegateSpec, 0, len(delegates))
+	for _, d := range delegates {
+		if d.Name == self {
+			continue
+		}
+		i := slices.IndexFunc(result, func(x cattagev1beta1.DelegateSpec) bool {
+			return x.Name == d.Name
+		})
+		if i < 0 {
+			result = append(result, cattagev1beta1.DelegateSpec{Name: d.Name, Roles: slices.Clone(d.Roles), Groups: slices.Clone(d.Groups)})
+			continue
+		}
+		result[i].Roles = append(result[i].Roles, d.Roles...)
+		result[i].Groups = append(result[i].Groups, d.Groups...)
+	}
+	for i := range result {
+		slices.Sort(result[i].Roles)
+		result[i].Roles = slices.Compact(result[i].Roles)
+		slices.Sort(result[i].Groups)
+		result[i].Groups = slices.Compact(result[i].Groups)
+	}
+	return result
+}
+
+// descendants returns the names of all descendants of the tenant.
+func (r *TenantReconciler) descendants(ctx context.Context, name string) ([]string, error) {
+	result := make([]string, 0)
+	queue := []string{name}
+	for len(queue) != 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		children := &cattagev1beta1.TenantList{}
+		if err := r.client.List(ctx, children, client.MatchingFields{constants.ParentTenantIndex: current}); err != nil {
+			return nil, fmt.Errorf("failed to list tenants: %w", err)
+		}
+		for _, c := range children.Items {
+			if c.Name == name || slices.Contains(result, c.Name) {
+				continue
+			}
+			result = append(result, c.Name)
+			queue = append(queue, c.Name)
+		}
+	}
+	return result, nil
+}
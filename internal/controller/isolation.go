@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	acmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+	acnetworkingv1 "k8s.io/client-go/applyconfigurations/networking/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// isolationPeers returns the names of tenants that are allowed to access the namespaces of the tenant.
+func isolationPeers(tenant *cattagev1beta1.Tenant) []string {
+	peers := []string{tenant.Name}
+	peers = append(peers, tenant.Spec.NetworkPeers...)
+	for _, d := range tenant.Spec.Delegates {
+		peers = append(peers, d.Name)
+	}
+	slices.Sort(peers)
+	return slices.Compact(peers)
+}
+
+func (r *TenantReconciler) isolationNetworkPolicy(cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string) *acnetworkingv1.NetworkPolicyApplyConfiguration {
+	ingress := []*acnetworkingv1.NetworkPolicyIngressRuleApplyConfiguration{
+		acnetworkingv1.NetworkPolicyIngressRule().WithFrom(
+			acnetworkingv1.NetworkPolicyPeer().WithNamespaceSelector(
+				acmetav1.LabelSelector().WithMatchExpressions(
+					acmetav1.LabelSelectorRequirement().
+						WithKey(constants.OwnerTenant).
+						WithOperator(metav1.LabelSelectorOpIn).
+						WithValues(isolationPeers(tenant)...),
+				),
+			),
+		),
+	}
+	if len(cfg.Isolation.AllowedNamespaces) != 0 {
+		allowed := slices.Clone(cfg.Isolation.AllowedNamespaces)
+		slices.Sort(allowed)
+		ingress = append(ingress, acnetworkingv1.NetworkPolicyIngressRule().WithFrom(
+			acnetworkingv1.NetworkPolicyPeer().WithNamespaceSelector(
+				acmetav1.LabelSelector().WithMatchExpressions(
+					acmetav1.LabelSelectorRequirement().
+						WithKey(corev1.LabelMetadataName).
+						WithOperator(metav1.LabelSelectorOpIn).
+						WithValues(allowed...),
+				),
+			),
+		))
+	}
+
+	return acnetworkingv1.NetworkPolicy(constants.IsolationNetworkPolicyName, namespace).
+		WithLabels(map[string]string{
+			constants.OwnerTenant: tenant.Name,
+		}).
+		WithSpec(acnetworkingv1.NetworkPolicySpec().
+			WithPodSelector(acmetav1.LabelSelector()).
+			WithPolicyTypes(networkingv1.PolicyTypeIngress).
+			WithIngress(ingress...),
+		)
+}
+
+func (r *TenantReconciler) reconcileIsolation(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) error {
+	namespaces := make([]string, 0)
+	if cfg.Isolation.Enabled {
+		nss := &corev1.NamespaceList{}
+		if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenant.Name}); err != nil {
+			return fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		for _, ns := range nss.Items {
+			if ns.DeletionTimestamp != nil {
+				continue
+			}
+			err := r.patchNetworkPolicy(ctx, r.isolationNetworkPolicy(cfg, tenant, ns.Name))
+			if err != nil {
+				return err
+			}
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+
+	nps := &networkingv1.NetworkPolicyList{}
+	if err := r.client.List(ctx, nps, client.MatchingLabels{constants.OwnerTenant: tenant.Name}); err != nil {
+		return fmt.Errorf("failed to list network policies: %w", err)
+	}
+	for _, np := range nps.Items {
+		if np.Name != constants.IsolationNetworkPolicyName || slices.Contains(namespaces, np.Namespace) {
+			continue
+		}
+		err := r.removeNetworkPolicy(ctx, &np)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TenantReconciler) removeIsolation(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
+	nps := &networkingv1.NetworkPolicyList{}
+	if err := r.client.List(ctx, nps, client.MatchingLabels{constants.OwnerTenant: tenant.Name}); err != nil {
+		return fmt.Errorf("failed to list network policies: %w", err)
+	}
+	for _, np := range nps.Items {
+		if np.Name != constants.IsolationNetworkPolicyName {
+			continue
+		}
+		err := r.removeNetworkPolicy(ctx, &np)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TenantReconciler) removeNetworkPolicy(ctx context.Context, np *networkingv1.NetworkPolicy) error {
+	logger := log.FromContext(ctx)
+	if np.DeletionTimestamp != nil {
+		return nil
+	}
+	err := r.client.Delete(ctx, np)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("NetworkPolicy deleted", "networkpolicy", np.Name, "namespace", np.Namespace)
+	return nil
+}
+
+func (r *TenantReconciler) patchNetworkPolicy(ctx context.Context, np *acnetworkingv1.NetworkPolicyApplyConfiguration) error {
+	logger := log.FromContext(ctx)
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(np)
+	if err != nil {
+		return err
+	}
+	patch := &unstructured.Unstructured{
+		Object: obj,
+	}
+
+	var orig networkingv1.NetworkPolicy
+	err = r.client.Get(ctx, client.ObjectKey{Namespace: *np.Namespace, Name: *np.Name}, &orig)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	managed, err := acnetworkingv1.ExtractNetworkPolicy(&orig, constants.TenantFieldManager)
+	if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(np, managed) {
+		return nil
+	}
+
+	logger.Info("patching NetworkPolicy", "networkpolicy", np, "managed", managed)
+	return r.client.Patch(ctx, patch, client.Apply, &client.PatchOptions{
+		FieldManager: constants.TenantFieldManager,
+		Force:        ptr.To(true),
+	})
+}
@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	"github.com/cybozu-go/cattage/internal/metrics"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// reconcileControllerAssignment assigns the tenant to an application-controller and records it in the status.
+// When the assignment changes, the namespaces of the tenant are removed from the old controller first,
+// and added to the new controller after the grace period passes or the migration is acknowledged.
+// It returns the time to wait for the grace period, or zero if no migration is in progress.
+//
+// resolved is updated in the same way as the status of the tenant.
+func (r *TenantReconciler) reconcileControllerAssignment(ctx context.Context, cfg *config.Config, tenant, resolved *cattagev1beta1.Tenant) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+
+	target, err := r.assignController(ctx, cfg, resolved)
+	if err != nil {
+		return 0, err
+	}
+
+	current := tenant.Status.ControllerName
+	migration := tenant.Status.Migration.DeepCopy()
+	switch {
+	case migration == nil && current != "" && current != target:
+		migration = &cattagev1beta1.ControllerMigration{
+			Phase:     cattagev1beta1.ControllerMigrationDraining,
+			From:      current,
+			To:        target,
+			StartTime: metav1.Now(),
+		}
+		logger.Info("starting migration between application controllers", "from", current, "to", target)
+		r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventControllerMigrationStarted, "Removed namespaces from application controller %s to migrate to %s", current, target)
+	case migration != nil && migration.From == target:
+		logger.Info("cancelling migration between application controllers", "from", migration.From, "to", migration.To)
+		r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventControllerMigrationCancelled, "Cancelled migration to application controller %s", migration.To)
+		migration = nil
+	case migration != nil:
+		// The old controller has already been drained, so the grace period is not restarted.
+		migration.To = target
+	}
+
+	var wait time.Duration
+	if migration != nil {
+		acked := tenant.Annotations[constants.MigrationAckAnnotation] == migration.To
+		wait = cfg.ArgoCD.Sharding.MigrationGracePeriod.Duration - time.Since(migration.StartTime.Time)
+		if acked || wait <= 0 {
+			if err := r.removeMigrationAck(ctx, tenant); err != nil {
+				return 0, err
+			}
+			logger.Info("finished migration between application controllers", "from", migration.From, "to", migration.To, "acknowledged", acked)
+			r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventControllerMigrationCompleted, "Migrated from application controller %s to %s", migration.From, migration.To)
+			metrics.ControllerMigrationsTotal.WithLabelValues(migration.From, migration.To).Inc()
+			migration = nil
+			wait = 0
+		}
+	}
+
+	tenant.Status.ControllerName = target
+	tenant.Status.Migration = migration
+	resolved.Status.ControllerName = target
+	resolved.Status.Migration = migration.DeepCopy()
+	return wait, nil
+}
+
+// removeMigrationAck removes the acknowledgement of the migration so that it does not apply to the next migration.
+// Only the resource version of the tenant is updated so that the status being reconciled is kept.
+func (r *TenantReconciler) removeMigrationAck(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
+	if _, ok := tenant.Annotations[constants.MigrationAckAnnotation]; !ok {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]any{
+		"metadata": map[string]any{
+			"annotations": map[string]any{
+				constants.MigrationAckAnnotation: nil,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	patched := &cattagev1beta1.Tenant{}
+	patched.Name = tenant.Name
+	if err := r.client.Patch(ctx, patched, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return err
+	}
+	delete(tenant.Annotations, constants.MigrationAckAnnotation)
+	tenant.ResourceVersion = patched.ResourceVersion
+	return nil
+}
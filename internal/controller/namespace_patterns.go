@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"path"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/accurate"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// compactNamespaces replaces the namespaces of the tenant matching its namespace patterns with the patterns.
+// A pattern that matches a namespace not belonging to the tenant is not used,
+// so that Argo CD does not accept applications in the namespace for the tenant.
+// all is the list of all namespaces in the cluster.
+func compactNamespaces(tenant *cattagev1beta1.Tenant, namespaces []string, all []corev1.Namespace) []string {
+	patterns := usableNamespacePatterns(tenant, all)
+	if len(patterns) == 0 {
+		return namespaces
+	}
+
+	result := slices.Clone(patterns)
+	for _, ns := range namespaces {
+		if !slices.ContainsFunc(patterns, func(pattern string) bool {
+			ok, _ := path.Match(pattern, ns)
+			return ok
+		}) {
+			result = append(result, ns)
+		}
+	}
+	slices.Sort(result)
+	return slices.Compact(result)
+}
+
+// usableNamespacePatterns returns the namespace patterns of the tenant that match only namespaces belonging to the tenant.
+func usableNamespacePatterns(tenant *cattagev1beta1.Tenant, all []corev1.Namespace) []string {
+	byName := make(map[string]*corev1.Namespace, len(all))
+	for i := range all {
+		byName[all[i].Name] = &all[i]
+	}
+
+	var patterns []string
+	for _, pattern := range tenant.Spec.ArgoCD.NamespacePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			continue
+		}
+		if slices.ContainsFunc(all, func(ns corev1.Namespace) bool {
+			ok, _ := path.Match(pattern, ns.Name)
+			return ok && namespaceOwner(&ns, byName) != tenant.Name
+		}) {
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns
+}
+
+// namespaceOwner returns the name of the tenant that the namespace belongs to.
+// The owner of a sub-namespace is taken from its root namespace by following the parents,
+// because the owner label is propagated to sub-namespaces asynchronously.
+func namespaceOwner(ns *corev1.Namespace, byName map[string]*corev1.Namespace) string {
+	current := ns
+	// The number of steps is bounded in case the parents form a cycle.
+	for range len(byName) {
+		if current.Labels[accurate.LabelType] == accurate.NSTypeRoot {
+			return current.Labels[constants.OwnerTenant]
+		}
+		parent, ok := byName[current.Labels[accurate.LabelParent]]
+		if !ok {
+			break
+		}
+		current = parent
+	}
+	return ns.Labels[constants.OwnerTenant]
+}
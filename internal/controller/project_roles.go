@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// readOnlyPolicy is the policy of the read-only roles of delegated tenants.
+var readOnlyPolicy = cattagev1beta1.ProjectPolicySpec{
+	Resources: []string{"applications", "applicationsets", "logs"},
+	Verbs:     []string{"get"},
+}
+
+// projectRole is a role of an AppProject.
+type projectRole struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Policies    []string `json:"policies,omitempty"`
+	Groups      []string `json:"groups,omitempty"`
+}
+
+// projectPolicies renders the policies in the format of Argo CD RBAC.
+func projectPolicies(project, role string, policies []cattagev1beta1.ProjectPolicySpec) []string {
+	result := make([]string, 0)
+	for _, p := range policies {
+		apps := p.Applications
+		if len(apps) == 0 {
+			apps = []string{"*"}
+		}
+		effect := p.Effect
+		if effect == "" {
+			effect = cattagev1beta1.PolicyEffectAllow
+		}
+		for _, res := range p.Resources {
+			for _, verb := range p.Verbs {
+				for _, app := range apps {
+					result = append(result, fmt.Sprintf("p, proj:%s:%s, %s, %s, %s/%s, %s", project, role, res, verb, project, app, effect))
+				}
+			}
+		}
+	}
+	return result
+}
+
+// projectRoles returns the roles declared in the tenant and the read-only roles of its delegated tenants.
+// The groups of a read-only role are `groups` of the delegate if specified, otherwise `argocd.delegateGroups` in the configuration.
+// They are never taken from the spec of the delegated tenant itself.
+func projectRoles(cfg *config.Config, tenant *cattagev1beta1.Tenant) ([]projectRole, error) {
+	result := make([]projectRole, 0, len(tenant.Spec.ArgoCD.Roles)+len(tenant.Spec.Delegates))
+	for _, role := range tenant.Spec.ArgoCD.Roles {
+		result = append(result, projectRole{
+			Name:        role.Name,
+			Description: role.Description,
+			Policies:    projectPolicies(tenant.Name, role.Name, role.Policies),
+			Groups:      slices.Clone(role.Groups),
+		})
+	}
+
+	delegates := slices.Clone(tenant.Spec.Delegates)
+	slices.SortFunc(delegates, func(a, b cattagev1beta1.DelegateSpec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	for _, d := range delegates {
+		groups := slices.Clone(d.Groups)
+		if len(groups) == 0 {
+			var err error
+			groups, err = cfg.DelegateGroups(d.Name)
+			if err != nil {
+				return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, fmt.Errorf("failed to render the groups of delegate %s: %w", d.Name, err))
+			}
+		}
+		if len(groups) == 0 {
+			continue
+		}
+		slices.Sort(groups)
+		roleName := constants.DelegateRolePrefix + d.Name
+		result = append(result, projectRole{
+			Name:        roleName,
+			Description: fmt.Sprintf("Read-only access for the delegated tenant %s", d.Name),
+			Policies:    projectPolicies(tenant.Name, roleName, []cattagev1beta1.ProjectPolicySpec{readOnlyPolicy}),
+			Groups:      slices.Compact(groups),
+		})
+	}
+	return result, nil
+}
+
+// mergeProjectRoles appends the roles to those of the AppProject rendered from the template.
+// The roles in the template take precedence over the roles with the same names.
+func mergeProjectRoles(ctx context.Context, proj *unstructured.Unstructured, roles []projectRole) error {
+	if len(roles) == 0 {
+		return nil
+	}
+	logger := log.FromContext(ctx)
+
+	current, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "roles")
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(current))
+	for _, role := range current {
+		if m, ok := role.(map[string]interface{}); ok {
+			if name, ok := m["name"].(string); ok {
+				names = append(names, name)
+			}
+		}
+	}
+
+	added := make([]projectRole, 0, len(roles))
+	for _, role := range roles {
+		if slices.Contains(names, role.Name) {
+			logger.Info("ignored project role defined in the template", "role", role.Name)
+			continue
+		}
+		added = append(added, role)
+	}
+	ret, err := toUnstructuredSlice(added)
+	if err != nil {
+		return err
+	}
+	return unstructured.SetNestedSlice(proj.UnstructuredContent(), append(current, ret...), "spec", "roles")
+}
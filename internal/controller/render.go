@@ -0,0 +1,484 @@
+package controller
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"text/template"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/accurate"
+	"github.com/cybozu-go/cattage/internal/argocd"
+	extract "github.com/cybozu-go/cattage/internal/client"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	accorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	acrbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RenderedObject is an object rendered from the templates in the same way as the reconciliation.
+type RenderedObject struct {
+	// Desired is the object that the controller applies.
+	Desired *unstructured.Unstructured
+
+	// Managed is the fields of the live object that are managed by the controller.
+	// This is nil if the object does not exist.
+	Managed map[string]interface{}
+}
+
+// Render renders the resources for the tenant without applying them.
+// The tenant does not need to exist in the cluster.
+// The Secrets of the repository credentials are not rendered so as not to reveal them.
+func (r *TenantReconciler) Render(ctx context.Context, tenant *cattagev1beta1.Tenant) ([]RenderedObject, error) {
+	cfg := r.config.Get()
+	result := make([]RenderedObject, 0)
+	add := func(obj any) error {
+		desired, err := toUnstructured(obj)
+		if err != nil {
+			return err
+		}
+		managed, err := r.managedFields(ctx, desired)
+		if err != nil {
+			return err
+		}
+		result = append(result, RenderedObject{Desired: desired, Managed: managed})
+		return nil
+	}
+
+	tenant, err := r.resolveTenant(ctx, cfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	params, err := r.namespaceTemplateParams(ctx, cfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range tenant.Spec.RootNamespaces {
+		resources, err := r.renderResources(cfg, tenant, ns.Name, params)
+		if err != nil {
+			return nil, err
+		}
+		refs := make([]resourceReference, len(resources))
+		for i, res := range resources {
+			refs[i] = referenceOf(res)
+		}
+		namespace, err := r.namespaceApplyConfiguration(cfg, tenant, ns, refs)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(namespace); err != nil {
+			return nil, err
+		}
+
+		rb, err := r.renderRoleBinding(cfg, tenant, ns.Name, params)
+		if err != nil {
+			return nil, err
+		}
+		if err := add(rb); err != nil {
+			return nil, err
+		}
+		quota, err := r.renderResourceQuota(cfg, tenant, ns.Name, params)
+		if err != nil {
+			return nil, err
+		}
+		if quota != nil {
+			if err := add(quota); err != nil {
+				return nil, err
+			}
+		}
+		lr, err := r.renderLimitRange(cfg, tenant, ns.Name, params)
+		if err != nil {
+			return nil, err
+		}
+		if lr != nil {
+			if err := add(lr); err != nil {
+				return nil, err
+			}
+		}
+		for _, res := range resources {
+			if err := add(res); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	proj, _, err := r.renderAppProject(ctx, cfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	if err := add(proj); err != nil {
+		return nil, err
+	}
+
+	controllerName, err := r.assignController(ctx, cfg, tenant)
+	if err != nil {
+		return nil, err
+	}
+	// The ConfigMaps are rendered as they will be after the migration between controllers, if any.
+	tenant.Status.ControllerName = controllerName
+	tenant.Status.Migration = nil
+	tenants, err := r.listTenantsForController(ctx, controllerName, tenant)
+	if err != nil {
+		return nil, err
+	}
+	cms, err := r.renderConfigMaps(ctx, cfg, tenant, tenants)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range cms {
+		desired, err := toUnstructured(cm)
+		if err != nil {
+			return nil, err
+		}
+		// The ConfigMap is not applied with server-side apply, so the live labels and data are compared.
+		live := &corev1.ConfigMap{}
+		err = r.client.Get(ctx, client.ObjectKeyFromObject(cm), live)
+		switch {
+		case apierrors.IsNotFound(err):
+			result = append(result, RenderedObject{Desired: desired})
+		case err != nil:
+			return nil, err
+		default:
+			managed, err := toUnstructured(&corev1.ConfigMap{
+				TypeMeta:   cm.TypeMeta,
+				ObjectMeta: cm.ObjectMeta,
+				Data:       live.Data,
+			})
+			if err != nil {
+				return nil, err
+			}
+			unstructured.RemoveNestedField(managed.Object, "metadata", "labels")
+			if live.Labels != nil {
+				labels := make(map[string]string)
+				for k := range cm.Labels {
+					if v, ok := live.Labels[k]; ok {
+						labels[k] = v
+					}
+				}
+				managed.SetLabels(labels)
+			}
+			result = append(result, RenderedObject{Desired: desired, Managed: managed.Object})
+		}
+	}
+
+	return result, nil
+}
+
+func toUnstructured(obj any) (*unstructured.Unstructured, error) {
+	if u, ok := obj.(*unstructured.Unstructured); ok {
+		return u, nil
+	}
+	m, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	return &unstructured.Unstructured{Object: m}, nil
+}
+
+func (r *TenantReconciler) managedFields(ctx context.Context, desired *unstructured.Unstructured) (map[string]interface{}, error) {
+	live := &unstructured.Unstructured{}
+	live.SetGroupVersionKind(desired.GroupVersionKind())
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: desired.GetNamespace(), Name: desired.GetName()}, live)
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	managed, err := extract.ExtractManagedFields(live, constants.TenantFieldManager)
+	if err != nil {
+		return nil, err
+	}
+	if desired.GetNamespace() == "" {
+		// ExtractManagedFields always sets the namespace, but cluster-scoped objects do not have it.
+		unstructured.RemoveNestedField(managed, "metadata", "namespace")
+	}
+	return managed, nil
+}
+
+func (r *TenantReconciler) namespaceTemplateParams(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) (namespaceTemplateParams, error) {
+	roles, err := r.rolesMap(ctx, cfg, tenant.Spec.Delegates)
+	if err != nil {
+		return namespaceTemplateParams{}, err
+	}
+	return namespaceTemplateParams{
+		Name:        tenant.Name,
+		Roles:       roles,
+		ExtraParams: tenant.Spec.ExtraParams.ToMap(),
+	}, nil
+}
+
+func (r *TenantReconciler) renderRoleBinding(cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string, params namespaceTemplateParams) (*acrbacv1.RoleBindingApplyConfiguration, error) {
+	buf, err := executeTemplate(cfg, "RoleBinding Template", cfg.Namespace.RoleBindingTemplate, params)
+	if err != nil {
+		return nil, err
+	}
+
+	rb := acrbacv1.RoleBinding(tenant.Name+"-admin", namespace)
+	err = k8syaml.Unmarshal(buf, rb)
+	if err != nil {
+		return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+	}
+	rb.WithLabels(map[string]string{
+		constants.OwnerTenant: tenant.Name,
+	})
+	rb.WithAnnotations(map[string]string{
+		accurate.AnnPropagate: accurate.PropagateUpdate,
+	})
+	return rb, nil
+}
+
+// renderResourceQuota returns nil if neither the tenant nor the configuration has the template.
+func (r *TenantReconciler) renderResourceQuota(cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string, params namespaceTemplateParams) (*accorev1.ResourceQuotaApplyConfiguration, error) {
+	text := tenant.Spec.ResourceQuotaTemplate
+	if text == "" {
+		text = cfg.Namespace.ResourceQuotaTemplate
+	}
+	if text == "" {
+		return nil, nil
+	}
+
+	buf, err := executeTemplate(cfg, "ResourceQuota Template", text, params)
+	if err != nil {
+		return nil, err
+	}
+	quota := accorev1.ResourceQuota(tenant.Name+"-quota", namespace)
+	err = k8syaml.Unmarshal(buf, quota)
+	if err != nil {
+		return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+	}
+	// The name and the namespace are fixed so that the controller can remove the ResourceQuota later.
+	quota.WithAPIVersion("v1").
+		WithKind("ResourceQuota").
+		WithName(tenant.Name + "-quota").
+		WithNamespace(namespace).
+		WithLabels(map[string]string{
+			constants.OwnerTenant: tenant.Name,
+		})
+	return quota, nil
+}
+
+// renderLimitRange returns nil if neither the tenant nor the configuration has the template.
+func (r *TenantReconciler) renderLimitRange(cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string, params namespaceTemplateParams) (*accorev1.LimitRangeApplyConfiguration, error) {
+	text := tenant.Spec.LimitRangeTemplate
+	if text == "" {
+		text = cfg.Namespace.LimitRangeTemplate
+	}
+	if text == "" {
+		return nil, nil
+	}
+
+	buf, err := executeTemplate(cfg, "LimitRange Template", text, params)
+	if err != nil {
+		return nil, err
+	}
+	lr := accorev1.LimitRange(tenant.Name+"-limitrange", namespace)
+	err = k8syaml.Unmarshal(buf, lr)
+	if err != nil {
+		return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+	}
+	// The name and the namespace are fixed so that the controller can remove the LimitRange later.
+	lr.WithAPIVersion("v1").
+		WithKind("LimitRange").
+		WithName(tenant.Name + "-limitrange").
+		WithNamespace(namespace).
+		WithLabels(map[string]string{
+			constants.OwnerTenant: tenant.Name,
+		})
+	return lr, nil
+}
+
+// renderAppProject renders the AppProject of the tenant and returns it with the SyncWindow resources reflected to it.
+func (r *TenantReconciler) renderAppProject(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) (*unstructured.Unstructured, []cattagev1beta1.SyncWindow, error) {
+	tpl, err := template.New("AppProject Template").Option(cfg.TemplateOptions()...).Parse(cfg.ArgoCD.AppProjectTemplate)
+	if err != nil {
+		return nil, nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+	}
+
+	namespaces, err := r.getTenantNamespaces(ctx, cfg, tenant)
+	if err != nil {
+		return nil, nil, withReason(cattagev1beta1.ReasonListFailed, err)
+	}
+
+	roles, err := r.rolesMap(ctx, cfg, tenant.Spec.Delegates)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ancestors, err := r.ancestors(ctx, tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	repos := r.allowedRepositories(ctx, cfg, tenant)
+
+	destinations := r.destinations(ctx, cfg, tenant, namespaces)
+
+	var buf bytes.Buffer
+	err = tpl.Execute(&buf, struct {
+		Name         string
+		Ancestors    []string
+		Namespaces   []string
+		Roles        map[string][]Role
+		Repositories []string
+		Destinations []Destination
+		ExtraParams  map[string]interface{}
+	}{
+		Name:         tenant.Name,
+		Ancestors:    ancestorNames(ancestors),
+		Namespaces:   namespaces,
+		Roles:        roles,
+		Repositories: repos,
+		Destinations: destinations,
+		ExtraParams:  tenant.Spec.ExtraParams.ToMap(),
+	})
+	if err != nil {
+		return nil, nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+	}
+
+	proj := argocd.AppProject()
+	dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+	_, _, err = dec.Decode(buf.Bytes(), nil, proj)
+	if err != nil {
+		return nil, nil, withReason(cattagev1beta1.ReasonInvalidTemplate, fmt.Errorf("failed to decode AppProject: %w", err))
+	}
+
+	proj.SetNamespace(cfg.ArgoCD.Namespace)
+	proj.SetName(tenant.Name)
+	proj.SetLabels(map[string]string{
+		constants.OwnerTenant: tenant.Name,
+	})
+	tenantRoles, err := projectRoles(cfg, tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := mergeProjectRoles(ctx, proj, tenantRoles); err != nil {
+		return nil, nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+	}
+	if err := r.applyResourcePolicy(ctx, cfg, proj, tenant); err != nil {
+		return nil, nil, err
+	}
+	val, found, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "syncWindows")
+	if err != nil {
+		return nil, nil, err
+	}
+	var syncWindows cattagev1beta1.SyncWindows
+	if !found {
+		syncWindows = cattagev1beta1.SyncWindows{}
+	} else {
+		syncWindows, err = fromUnstructuredSlice[cattagev1beta1.SyncWindows](val)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	csws, err := r.getClusterSyncWindows(ctx, tenant)
+	if err != nil {
+		return nil, nil, withCondition(cattagev1beta1.ConditionSyncWindowsReady, cattagev1beta1.ReasonListFailed, fmt.Errorf("failed to get cluster sync windows: %w", err))
+	}
+	syncWindows = append(syncWindows, csws...)
+	swResources, sws, err := r.getSyncWindows(ctx, tenant)
+	if err != nil {
+		return nil, nil, withCondition(cattagev1beta1.ConditionSyncWindowsReady, cattagev1beta1.ReasonListFailed, fmt.Errorf("failed to get sync windows: %w", err))
+	}
+	syncWindows = append(syncWindows, sws...)
+	if len(syncWindows) != 0 {
+		ret, err := toUnstructuredSlice[cattagev1beta1.SyncWindows](syncWindows)
+		if err != nil {
+			return nil, nil, err
+		}
+		err = unstructured.SetNestedSlice(proj.UnstructuredContent(), ret, "spec", "syncWindows")
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return proj, swResources, nil
+}
+
+// destinations returns the destination clusters of the tenant passed to the template for AppProject.
+// The clusters not allowed in the configuration are ignored, so that access to them is revoked when they are removed from the configuration.
+func (r *TenantReconciler) destinations(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, namespaces []string) []Destination {
+	logger := log.FromContext(ctx)
+
+	result := make([]Destination, 0, len(tenant.Spec.ArgoCD.Destinations))
+	for _, d := range tenant.Spec.ArgoCD.Destinations {
+		cluster, ok := cfg.AllowedCluster(d.Name, d.Server)
+		if !ok {
+			logger.Info("ignored destination cluster that is not allowed", "name", d.Name, "server", d.Server)
+			continue
+		}
+		nss := slices.Clone(d.Namespaces)
+		if len(nss) == 0 {
+			nss = slices.Clone(namespaces)
+		}
+		result = append(result, Destination{
+			Name:       cluster.Name,
+			Server:     cluster.Server,
+			Namespaces: nss,
+		})
+	}
+	return result
+}
+
+// listTenantsForController lists the tenants assigned to the application-controller.
+// If current is not nil, its assignment is used instead of the one in the cache because it may not be recorded yet.
+func (r *TenantReconciler) listTenantsForController(ctx context.Context, controllerName string, current *cattagev1beta1.Tenant) ([]cattagev1beta1.Tenant, error) {
+	tenantList := &cattagev1beta1.TenantList{}
+	if err := r.client.List(ctx, tenantList, client.MatchingFields{constants.ControllerNameIndex: controllerName}); err != nil {
+		return nil, fmt.Errorf("failed to list tenants: %w", err)
+	}
+	tenants := tenantList.Items
+	if current != nil {
+		tenants = slices.DeleteFunc(tenants, func(t cattagev1beta1.Tenant) bool {
+			return t.Name == current.Name
+		})
+		if current.Status.Migration == nil && tenantControllerName(current) == controllerName {
+			tenants = append(tenants, *current)
+		}
+	}
+	slices.SortFunc(tenants, func(x, y cattagev1beta1.Tenant) int {
+		return cmp.Compare(x.Name, y.Name)
+	})
+	return tenants, nil
+}
+
+// renderConfigMaps renders the ConfigMaps for the application-controller of the tenant that list the namespaces of the tenant.
+// tenants are the tenants assigned to the same application-controller.
+// The owner references are not set to the returned ConfigMaps.
+func (r *TenantReconciler) renderConfigMaps(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, tenants []cattagev1beta1.Tenant) ([]*corev1.ConfigMap, error) {
+	tns, err := listTenantNamespaces(ctx, r.client, cfg, tenants)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []*corev1.ConfigMap
+	for _, out := range cfg.OutputConfigMaps() {
+		if !out.PerController {
+			continue
+		}
+		ok, err := outputSelects(out, tenant)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		cms, err := renderOutputConfigMaps(cfg, out, tns)
+		if err != nil {
+			return nil, err
+		}
+		for _, cm := range cms {
+			result = append(result, cm.configMap)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,223 @@
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	accorev1 "k8s.io/client-go/applyconfigurations/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// repositoryCredentialParams is the data passed to the template for repository credentials.
+type repositoryCredentialParams struct {
+	Name        string
+	URL         string
+	Secret      map[string]string
+	ExtraParams map[string]interface{}
+}
+
+// repositoryCredentialPrefix returns the prefix of the names of the repository credentials of the tenant.
+func repositoryCredentialPrefix(tenant *cattagev1beta1.Tenant) string {
+	return tenant.Name + "-repo-"
+}
+
+// repositoryCredentialName returns a name of the repository credential that is unique for the URL.
+func repositoryCredentialName(tenant *cattagev1beta1.Tenant, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return repositoryCredentialPrefix(tenant) + hex.EncodeToString(sum[:])[:8]
+}
+
+// allowedRepositories returns the repositories of the tenant passed to the template for AppProject.
+// The repositories not allowed in the configuration are ignored, so that access to them is revoked when they are removed from the configuration.
+func (r *TenantReconciler) allowedRepositories(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) []string {
+	logger := log.FromContext(ctx)
+
+	repos := make([]string, 0, len(tenant.Spec.ArgoCD.Repositories))
+	for _, repo := range tenant.Spec.ArgoCD.Repositories {
+		if !cfg.IsAllowedRepository(repo, tenant.Labels) {
+			logger.Info("ignored repository that is not allowed", "repository", repo)
+			continue
+		}
+		repos = append(repos, repo)
+	}
+	slices.Sort(repos)
+	return slices.Compact(repos)
+}
+
+// renderRepositoryCredential renders the Secret of the repository credential for Argo CD.
+// The URL and the project of the credential are always set by the controller, so that the template cannot widen its scope.
+func (r *TenantReconciler) renderRepositoryCredential(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, cred cattagev1beta1.RepositoryCredentialSpec) (*accorev1.SecretApplyConfiguration, error) {
+	ns := &corev1.Namespace{}
+	err := r.client.Get(ctx, client.ObjectKey{Name: cred.SecretRef.Namespace}, ns)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return nil, err
+	}
+	if apierrors.IsNotFound(err) || ns.Labels[constants.OwnerTenant] != tenant.Name {
+		return nil, withReason(cattagev1beta1.ReasonSecretNotFound, fmt.Errorf("namespace %s does not belong to the tenant", cred.SecretRef.Namespace))
+	}
+
+	source := &corev1.Secret{}
+	err = r.client.Get(ctx, client.ObjectKey{Namespace: cred.SecretRef.Namespace, Name: cred.SecretRef.Name}, source)
+	if apierrors.IsNotFound(err) {
+		return nil, withReason(cattagev1beta1.ReasonSecretNotFound, fmt.Errorf("secret %s/%s is not found", cred.SecretRef.Namespace, cred.SecretRef.Name))
+	}
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string]string, len(source.Data))
+	for k, v := range source.Data {
+		data[k] = string(v)
+	}
+
+	buf, err := executeTemplate(cfg, "Repository Credential Template", cfg.ArgoCD.Repositories.CredentialTemplate, repositoryCredentialParams{
+		Name:        tenant.Name,
+		URL:         cred.URL,
+		Secret:      data,
+		ExtraParams: tenant.Spec.ExtraParams.ToMap(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	secret := &accorev1.SecretApplyConfiguration{}
+	err = k8syaml.Unmarshal(buf, secret)
+	if err != nil {
+		return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, fmt.Errorf("failed to decode Secret: %w", err))
+	}
+	secret.WithAPIVersion("v1").
+		WithKind("Secret").
+		WithName(repositoryCredentialName(tenant, cred.URL)).
+		WithNamespace(cfg.ArgoCD.Namespace).
+		WithLabels(map[string]string{
+			constants.OwnerTenant: tenant.Name,
+		})
+
+	// stringData is write-only, so it is merged into data to compare the Secret with the managed fields.
+	for k, v := range secret.StringData {
+		secret.WithData(map[string][]byte{k: []byte(v)})
+	}
+	secret.StringData = nil
+	secret.WithData(map[string][]byte{
+		"url":     []byte(cred.URL),
+		"project": []byte(tenant.Name),
+	})
+	return secret, nil
+}
+
+func (r *TenantReconciler) reconcileRepositoryCredentials(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) error {
+	logger := log.FromContext(ctx)
+
+	names := make([]string, 0)
+	if cfg.ArgoCD.Repositories.CredentialTemplate != "" {
+		for _, cred := range tenant.Spec.ArgoCD.RepositoryCredentials {
+			if !cfg.IsAllowedRepository(cred.URL, tenant.Labels) {
+				logger.Info("ignored repository credential that is not allowed", "repository", cred.URL)
+				continue
+			}
+			secret, err := r.renderRepositoryCredential(ctx, cfg, tenant, cred)
+			if err != nil {
+				return err
+			}
+			err = r.patchSecret(ctx, secret)
+			if err != nil {
+				return err
+			}
+			names = append(names, *secret.Name)
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := r.client.List(ctx, secrets, client.InNamespace(cfg.ArgoCD.Namespace), client.MatchingLabels{constants.OwnerTenant: tenant.Name}); err != nil {
+		return withReason(cattagev1beta1.ReasonListFailed, fmt.Errorf("failed to list secrets: %w", err))
+	}
+	for _, secret := range secrets.Items {
+		if !strings.HasPrefix(secret.Name, repositoryCredentialPrefix(tenant)) || slices.Contains(names, secret.Name) {
+			continue
+		}
+		err := r.removeSecret(ctx, &secret)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TenantReconciler) removeRepositoryCredentials(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) error {
+	secrets := &corev1.SecretList{}
+	if err := r.client.List(ctx, secrets, client.InNamespace(cfg.ArgoCD.Namespace), client.MatchingLabels{constants.OwnerTenant: tenant.Name}); err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	for _, secret := range secrets.Items {
+		if !strings.HasPrefix(secret.Name, repositoryCredentialPrefix(tenant)) {
+			continue
+		}
+		err := r.removeSecret(ctx, &secret)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TenantReconciler) removeSecret(ctx context.Context, secret *corev1.Secret) error {
+	logger := log.FromContext(ctx)
+	if secret.DeletionTimestamp != nil {
+		return nil
+	}
+	err := r.client.Delete(ctx, secret)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logger.Info("Secret deleted", "secret", secret.Name, "namespace", secret.Namespace)
+	return nil
+}
+
+// patchSecret applies the Secret. The data of the Secret are not logged.
+func (r *TenantReconciler) patchSecret(ctx context.Context, secret *accorev1.SecretApplyConfiguration) error {
+	logger := log.FromContext(ctx)
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(secret)
+	if err != nil {
+		return err
+	}
+	patch := &unstructured.Unstructured{
+		Object: obj,
+	}
+
+	var orig corev1.Secret
+	err = r.client.Get(ctx, client.ObjectKey{Namespace: *secret.Namespace, Name: *secret.Name}, &orig)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	managed, err := accorev1.ExtractSecret(&orig, constants.TenantFieldManager)
+	if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(secret, managed) {
+		return nil
+	}
+
+	logger.Info("patching Secret", "secret", *secret.Name, "namespace", *secret.Namespace)
+	return r.client.Patch(ctx, patch, client.Apply, &client.PatchOptions{
+		FieldManager: constants.TenantFieldManager,
+		Force:        ptr.To(true),
+	})
+}
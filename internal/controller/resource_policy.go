@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"path"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// allGroupKinds matches all resources in the lists of AppProjects.
+var allGroupKinds = metav1.GroupKind{Group: "*", Kind: "*"}
+
+// groupKindAllowed returns true if gk is matched by one of the patterns.
+// Argo CD matches the groups and the kinds in the lists of AppProjects as glob patterns.
+func groupKindAllowed(patterns []metav1.GroupKind, gk metav1.GroupKind) bool {
+	return slices.ContainsFunc(patterns, func(p metav1.GroupKind) bool {
+		groupOK, _ := path.Match(p.Group, gk.Group)
+		kindOK, _ := path.Match(p.Kind, gk.Kind)
+		return groupOK && kindOK
+	})
+}
+
+// mergeGroupKinds returns the union of the lists keeping the order of the first appearance.
+func mergeGroupKinds(lists ...[]metav1.GroupKind) []metav1.GroupKind {
+	result := make([]metav1.GroupKind, 0)
+	for _, list := range lists {
+		for _, gk := range list {
+			if !slices.Contains(result, gk) {
+				result = append(result, gk)
+			}
+		}
+	}
+	return result
+}
+
+// narrowWhitelist returns the entries of the tenant allowed by the whitelist.
+// If emptyAllowsAll is true, an empty whitelist allows any resource as namespaceResourceWhitelist of AppProjects does.
+func narrowWhitelist(ctx context.Context, whitelist, entries []metav1.GroupKind, emptyAllowsAll bool) []metav1.GroupKind {
+	logger := log.FromContext(ctx)
+	if len(whitelist) == 0 && emptyAllowsAll {
+		return mergeGroupKinds(entries)
+	}
+	result := make([]metav1.GroupKind, 0, len(entries))
+	for _, gk := range entries {
+		if !groupKindAllowed(whitelist, gk) {
+			logger.Info("ignored resource that is not allowed", "group", gk.Group, "kind", gk.Kind)
+			continue
+		}
+		result = append(result, gk)
+	}
+	return mergeGroupKinds(result)
+}
+
+// resourcePolicyLists is the lists of resources of an AppProject.
+type resourcePolicyLists struct {
+	ClusterResourceWhitelist   []metav1.GroupKind
+	ClusterResourceBlacklist   []metav1.GroupKind
+	NamespaceResourceWhitelist []metav1.GroupKind
+	NamespaceResourceBlacklist []metav1.GroupKind
+}
+
+// applyResourcePolicy merges the resource policy in the configuration and that of the tenant into the AppProject.
+// The lists of the policy in the configuration are added to those rendered from the template.
+// The blacklists of the tenant are added to them, and the whitelists of the tenant narrow them.
+func (r *TenantReconciler) applyResourcePolicy(ctx context.Context, cfg *config.Config, proj *unstructured.Unstructured, tenant *cattagev1beta1.Tenant) error {
+	spec := tenant.Spec.ArgoCD.ResourcePolicy
+	if spec == nil {
+		spec = &cattagev1beta1.ResourcePolicySpec{}
+	}
+	profile, err := cfg.ResourcePolicy(spec.Profile, tenant.Labels)
+	if err != nil {
+		return withReason(cattagev1beta1.ReasonResourcePolicyNotFound, err)
+	}
+	if profile == nil {
+		profile = &config.ResourcePolicyConfig{}
+	}
+
+	var current resourcePolicyLists
+	fields := []struct {
+		name string
+		list *[]metav1.GroupKind
+	}{
+		{"clusterResourceWhitelist", &current.ClusterResourceWhitelist},
+		{"clusterResourceBlacklist", &current.ClusterResourceBlacklist},
+		{"namespaceResourceWhitelist", &current.NamespaceResourceWhitelist},
+		{"namespaceResourceBlacklist", &current.NamespaceResourceBlacklist},
+	}
+	for _, f := range fields {
+		val, found, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", f.name)
+		if err != nil {
+			return withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+		}
+		if !found {
+			continue
+		}
+		*f.list, err = fromUnstructuredSlice[[]metav1.GroupKind](val)
+		if err != nil {
+			return withReason(cattagev1beta1.ReasonInvalidTemplate, err)
+		}
+	}
+
+	current.ClusterResourceWhitelist = mergeGroupKinds(current.ClusterResourceWhitelist, profile.ClusterResourceWhitelist)
+	current.ClusterResourceBlacklist = mergeGroupKinds(current.ClusterResourceBlacklist, profile.ClusterResourceBlacklist, spec.ClusterResourceBlacklist)
+	current.NamespaceResourceWhitelist = mergeGroupKinds(current.NamespaceResourceWhitelist, profile.NamespaceResourceWhitelist)
+	current.NamespaceResourceBlacklist = mergeGroupKinds(current.NamespaceResourceBlacklist, profile.NamespaceResourceBlacklist, spec.NamespaceResourceBlacklist)
+	if len(spec.ClusterResourceWhitelist) != 0 {
+		current.ClusterResourceWhitelist = narrowWhitelist(ctx, current.ClusterResourceWhitelist, spec.ClusterResourceWhitelist, false)
+	}
+	if len(spec.NamespaceResourceWhitelist) != 0 {
+		current.NamespaceResourceWhitelist = narrowWhitelist(ctx, current.NamespaceResourceWhitelist, spec.NamespaceResourceWhitelist, true)
+		// An empty namespaceResourceWhitelist allows any resource, so all resources are denied explicitly instead.
+		if len(current.NamespaceResourceWhitelist) == 0 {
+			current.NamespaceResourceBlacklist = mergeGroupKinds(current.NamespaceResourceBlacklist, []metav1.GroupKind{allGroupKinds})
+		}
+	}
+
+	for _, f := range fields {
+		if len(*f.list) == 0 {
+			unstructured.RemoveNestedField(proj.UnstructuredContent(), "spec", f.name)
+			continue
+		}
+		ret, err := toUnstructuredSlice(*f.list)
+		if err != nil {
+			return err
+		}
+		if err := unstructured.SetNestedSlice(proj.UnstructuredContent(), ret, "spec", f.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	extract "github.com/cybozu-go/cattage/internal/client"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// resourceReference identifies a resource that is created from `namespace.resourceTemplates`.
+// The references are recorded in the annotation of root namespaces to garbage-collect resources
+// whose templates have been removed from the configuration.
+type resourceReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+}
+
+func referenceOf(obj *unstructured.Unstructured) resourceReference {
+	return resourceReference{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Name:       obj.GetName(),
+	}
+}
+
+func compareResourceReferences(x, y resourceReference) int {
+	if c := cmp.Compare(x.APIVersion, y.APIVersion); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(x.Kind, y.Kind); c != 0 {
+		return c
+	}
+	return cmp.Compare(x.Name, y.Name)
+}
+
+func mergeResourceReferences(refs ...[]resourceReference) []resourceReference {
+	result := make([]resourceReference, 0)
+	for _, r := range refs {
+		result = append(result, r...)
+	}
+	slices.SortFunc(result, compareResourceReferences)
+	return slices.Compact(result)
+}
+
+func appliedResources(ns *corev1.Namespace) ([]resourceReference, error) {
+	val := ns.GetAnnotations()[constants.ResourcesAnnotation]
+	if val == "" {
+		return nil, nil
+	}
+	var refs []resourceReference
+	err := json.Unmarshal([]byte(val), &refs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s annotation of namespace %s: %w", constants.ResourcesAnnotation, ns.Name, err)
+	}
+	return refs, nil
+}
+
+func encodeResourceReferences(refs []resourceReference) (string, error) {
+	data, err := json.Marshal(mergeResourceReferences(refs))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (r *TenantReconciler) renderResources(cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string, params namespaceTemplateParams) ([]*unstructured.Unstructured, error) {
+	resources := make([]*unstructured.Unstructured, 0, len(cfg.Namespace.ResourceTemplates))
+	for _, rt := range cfg.Namespace.ResourceTemplates {
+		buf, err := executeTemplate(cfg, rt.Name, rt.Template, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute resource template %s: %w", rt.Name, err)
+		}
+		// A template may render nothing to skip creating the resource for some tenants.
+		if len(bytes.TrimSpace(buf)) == 0 {
+			continue
+		}
+
+		obj := &unstructured.Unstructured{}
+		dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
+		_, _, err = dec.Decode(buf, nil, obj)
+		if err != nil {
+			return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, fmt.Errorf("failed to decode resource template %s: %w", rt.Name, err))
+		}
+		if obj.GetName() == "" {
+			return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, fmt.Errorf("resource template %s does not have metadata.name", rt.Name))
+		}
+		obj.SetNamespace(namespace)
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[constants.OwnerTenant] = tenant.Name
+		labels[constants.ResourceTemplateLabel] = rt.Name
+		obj.SetLabels(labels)
+		resources = append(resources, obj)
+	}
+	return resources, nil
+}
+
+func (r *TenantReconciler) patchResource(ctx context.Context, obj *unstructured.Unstructured) error {
+	logger := log.FromContext(ctx)
+
+	orig := &unstructured.Unstructured{}
+	orig.SetGroupVersionKind(obj.GroupVersionKind())
+	err := r.apiReader.Get(ctx, client.ObjectKey{Namespace: obj.GetNamespace(), Name: obj.GetName()}, orig)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	managed, err := extract.ExtractManagedFields(orig, constants.TenantFieldManager)
+	if err != nil {
+		return err
+	}
+	if equality.Semantic.DeepEqual(obj.Object, managed) {
+		return nil
+	}
+
+	logger.Info("patching resource", "kind", obj.GetKind(), "name", obj.GetName(), "namespace", obj.GetNamespace())
+	return r.client.Patch(ctx, obj, client.Apply, &client.PatchOptions{
+		FieldManager: constants.TenantFieldManager,
+		Force:        ptr.To(true),
+	})
+}
+
+func (r *TenantReconciler) removeResource(ctx context.Context, tenant *cattagev1beta1.Tenant, namespace string, ref resourceReference) error {
+	logger := log.FromContext(ctx)
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion(ref.APIVersion)
+	obj.SetKind(ref.Kind)
+	err := r.apiReader.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ref.Name}, obj)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		return nil
+	}
+	labels := obj.GetLabels()
+	if labels == nil || labels[constants.OwnerTenant] != tenant.Name {
+		return nil
+	}
+	err = r.client.Delete(ctx, obj)
+	if err != nil {
+		return err
+	}
+	logger.Info("resource deleted", "kind", ref.Kind, "name", ref.Name, "namespace", namespace)
+	return nil
+}
+
+func (r *TenantReconciler) removeResources(ctx context.Context, tenant *cattagev1beta1.Tenant, ns *corev1.Namespace) error {
+	refs, err := appliedResources(ns)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		err := r.removeResource(ctx, tenant, ns.Name, ref)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/argocd"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// assignController returns the name of the application-controller that the tenant should be assigned to.
+// `controllerName` of the tenant takes precedence over the sharding policy in the configuration.
+func (r *TenantReconciler) assignController(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) (string, error) {
+	if tenant.Spec.ControllerName != "" {
+		return tenant.Spec.ControllerName, nil
+	}
+
+	switch cfg.ArgoCD.Sharding.Policy {
+	case config.ShardingPolicyLabelSelector:
+		for _, rule := range cfg.ArgoCD.Sharding.Rules {
+			selector, err := metav1.LabelSelectorAsSelector(&rule.TenantSelector)
+			if err != nil {
+				return "", err
+			}
+			if selector.Matches(labels.Set(tenant.Labels)) {
+				return rule.ControllerName, nil
+			}
+		}
+	case config.ShardingPolicyBalanced:
+		return r.balanceController(ctx, tenant, cfg)
+	}
+	return constants.DefaultApplicationControllerName, nil
+}
+
+// balanceController returns the least loaded application-controller.
+// The current assignment is kept as long as the controller exists, so that applications do not move between controllers.
+func (r *TenantReconciler) balanceController(ctx context.Context, tenant *cattagev1beta1.Tenant, cfg *config.Config) (string, error) {
+	controllers := cfg.ControllerNames()
+	if slices.Contains(controllers, tenant.Status.ControllerName) {
+		return tenant.Status.ControllerName, nil
+	}
+
+	assigned := ""
+	minLoad := 0
+	for _, name := range controllers {
+		tenants, err := r.listTenantsForController(ctx, name, nil)
+		if err != nil {
+			return "", err
+		}
+		load := 0
+		for _, t := range tenants {
+			if t.Name == tenant.Name {
+				continue
+			}
+			n, err := r.tenantLoad(ctx, &t, cfg.ArgoCD.Sharding.BalanceBy)
+			if err != nil {
+				return "", err
+			}
+			load += n
+		}
+		if assigned == "" || load < minLoad {
+			assigned = name
+			minLoad = load
+		}
+	}
+	return assigned, nil
+}
+
+// tenantLoad returns the load of the tenant on the application-controller.
+func (r *TenantReconciler) tenantLoad(ctx context.Context, tenant *cattagev1beta1.Tenant, balanceBy config.BalanceBy) (int, error) {
+	nss := &corev1.NamespaceList{}
+	if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenant.Name}); err != nil {
+		return 0, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	if balanceBy != config.BalanceByApplications {
+		return len(nss.Items), nil
+	}
+
+	load := 0
+	for _, ns := range nss.Items {
+		apps := argocd.ApplicationList()
+		if err := r.client.List(ctx, apps, client.InNamespace(ns.Name)); err != nil {
+			return 0, fmt.Errorf("failed to list applications: %w", err)
+		}
+		load += len(apps.Items)
+	}
+	return load, nil
+}
@@ -0,0 +1,131 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/constants"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// reconcileError is an error annotated with the condition and the reason reported in the status of Tenant.
+type reconcileError struct {
+	// conditionType overrides the condition that the caller reports the error on, if not empty.
+	conditionType string
+	reason        string
+	err           error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.err
+}
+
+// withReason annotates err with the reason reported in the status condition.
+func withReason(reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reconcileError{reason: reason, err: err}
+}
+
+// withCondition annotates err with the condition and the reason reported in the status.
+func withCondition(conditionType, reason string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &reconcileError{conditionType: conditionType, reason: reason, err: err}
+}
+
+// setFailedCondition records err on the condition of conditionType and marks the tenant as unhealthy.
+// A warning event is also recorded with the same reason as the condition.
+// It returns err as is so that the caller can return it directly.
+func (r *TenantReconciler) setFailedCondition(tenant *cattagev1beta1.Tenant, conditionType string, err error) error {
+	var re *reconcileError
+	if errors.As(err, &re) && re.conditionType != "" {
+		conditionType = re.conditionType
+	}
+	reason := failureReason(err)
+
+	tenant.Status.Health = cattagev1beta1.TenantUnhealthy
+	meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionFalse,
+		Reason:             reason,
+		Message:            err.Error(),
+		ObservedGeneration: tenant.Generation,
+	})
+	meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
+		Type:    cattagev1beta1.ConditionReady,
+		Status:  metav1.ConditionFalse,
+		Reason:  "Failed",
+		Message: err.Error(),
+	})
+	r.recorder.Event(tenant, corev1.EventTypeWarning, reason, err.Error())
+	return err
+}
+
+// failureReason returns the reason annotated to err. ApplyFailed is returned if err is not annotated.
+func failureReason(err error) string {
+	var re *reconcileError
+	if errors.As(err, &re) {
+		return re.reason
+	}
+	return cattagev1beta1.ReasonApplyFailed
+}
+
+func setReconciledCondition(tenant *cattagev1beta1.Tenant, conditionType string) {
+	meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             metav1.ConditionTrue,
+		Reason:             cattagev1beta1.ReasonReconciled,
+		ObservedGeneration: tenant.Generation,
+	})
+}
+
+// updateNamespacesStatus records the namespaces that belong to the tenant and its delegates in the status.
+// delegates should include those inherited from the ancestors of the tenant.
+func (r *TenantReconciler) updateNamespacesStatus(ctx context.Context, tenant *cattagev1beta1.Tenant, delegates []cattagev1beta1.DelegateSpec) error {
+	roots := &corev1.NamespaceList{}
+	if err := r.client.List(ctx, roots, client.MatchingFields{constants.RootNamespaceIndex: tenant.Name}); err != nil {
+		return withReason(cattagev1beta1.ReasonListFailed, fmt.Errorf("failed to list namespaces: %w", err))
+	}
+	nss := &corev1.NamespaceList{}
+	if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenant.Name}); err != nil {
+		return withReason(cattagev1beta1.ReasonListFailed, fmt.Errorf("failed to list namespaces: %w", err))
+	}
+	delegated, err := r.getDelegatedNamespaces(ctx, delegates)
+	if err != nil {
+		return withReason(cattagev1beta1.ReasonListFailed, fmt.Errorf("failed to list delegated namespaces: %w", err))
+	}
+
+	tenant.Status.RootNamespaces = namespaceNames(roots.Items)
+	tenant.Status.Namespaces = namespaceNames(nss.Items)
+	slices.Sort(delegated)
+	tenant.Status.DelegatedNamespaces = slices.Compact(delegated)
+	if len(tenant.Status.DelegatedNamespaces) == 0 {
+		tenant.Status.DelegatedNamespaces = nil
+	}
+	return nil
+}
+
+func namespaceNames(nss []corev1.Namespace) []string {
+	if len(nss) == 0 {
+		return nil
+	}
+	names := make([]string, len(nss))
+	for i, ns := range nss {
+		names[i] = ns.Name
+	}
+	slices.Sort(names)
+	return names
+}
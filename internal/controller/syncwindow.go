@@ -0,0 +1,285 @@
+package controller
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"path"
+	"slices"
+	"time"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
+	"github.com/cybozu-go/cattage/internal/metrics"
+	"github.com/cybozu-go/cattage/internal/syncwindow"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// scopeSyncWindows narrows the sync windows so that they apply only to the applications in the namespace.
+// It returns the narrowed sync windows and the number of the windows that are narrowed and dropped.
+//
+// A narrowed window matches the namespace with the AND operator in addition to its original conditions.
+// Because Argo CD combines applications, namespaces and clusters with the OR operator by default,
+// a window using the OR operator is split into a window per condition.
+func scopeSyncWindows(namespace string, windows cattagev1beta1.SyncWindows) (result cattagev1beta1.SyncWindows, narrowed, dropped int) {
+	result = cattagev1beta1.SyncWindows{}
+	for _, w := range windows {
+		if w == nil {
+			continue
+		}
+		scoped := scopeSyncWindow(namespace, w)
+		switch {
+		case len(scoped) == 0:
+			dropped++
+		case len(scoped) != 1 || !equality.Semantic.DeepEqual(scoped[0], w):
+			narrowed++
+		}
+		result = append(result, scoped...)
+	}
+	return result, narrowed, dropped
+}
+
+func scopeSyncWindow(namespace string, w *cattagev1beta1.SyncWindowSetting) cattagev1beta1.SyncWindows {
+	matchNamespace := slices.ContainsFunc(w.Namespaces, func(pattern string) bool {
+		ok, _ := path.Match(pattern, namespace)
+		return ok
+	})
+
+	if w.UseAndOperator {
+		if len(w.Namespaces) != 0 && !matchNamespace {
+			return nil
+		}
+		scoped := w.DeepCopy()
+		scoped.Namespaces = []string{namespace}
+		return cattagev1beta1.SyncWindows{scoped}
+	}
+
+	// All applications in the namespace match the window.
+	if matchNamespace {
+		scoped := w.DeepCopy()
+		scoped.Applications = nil
+		scoped.Clusters = nil
+		scoped.Namespaces = []string{namespace}
+		scoped.UseAndOperator = false
+		return cattagev1beta1.SyncWindows{scoped}
+	}
+
+	result := cattagev1beta1.SyncWindows{}
+	if len(w.Applications) != 0 {
+		scoped := w.DeepCopy()
+		scoped.Clusters = nil
+		scoped.Namespaces = []string{namespace}
+		scoped.UseAndOperator = true
+		result = append(result, scoped)
+	}
+	if len(w.Clusters) != 0 {
+		scoped := w.DeepCopy()
+		scoped.Applications = nil
+		scoped.Namespaces = []string{namespace}
+		scoped.UseAndOperator = true
+		result = append(result, scoped)
+	}
+	return result
+}
+
+// getClusterSyncWindows returns the sync windows in the ClusterSyncWindow resources that select the tenant.
+// The resources are sorted by name, and invalid ones are ignored.
+func (r *TenantReconciler) getClusterSyncWindows(ctx context.Context, tenant *cattagev1beta1.Tenant) (cattagev1beta1.SyncWindows, error) {
+	logger := log.FromContext(ctx)
+
+	csws := &cattagev1beta1.ClusterSyncWindowList{}
+	if err := r.client.List(ctx, csws); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(csws.Items, func(x, y cattagev1beta1.ClusterSyncWindow) int {
+		return cmp.Compare(x.Name, y.Name)
+	})
+
+	syncWindows := cattagev1beta1.SyncWindows{}
+	for _, csw := range csws.Items {
+		if errs := syncwindow.ValidateCluster(&csw); len(errs) != 0 {
+			logger.Info("ignoring invalid ClusterSyncWindow", "name", csw.Name, "error", errs.ToAggregate().Error())
+			continue
+		}
+		if !selectsTenant(&csw, tenant) {
+			continue
+		}
+		syncWindows = append(syncWindows, csw.Spec.SyncWindows...)
+	}
+	return syncWindows, nil
+}
+
+// selectsTenant returns true if the ClusterSyncWindow resource applies to the tenant.
+// The selector must be valid.
+func selectsTenant(csw *cattagev1beta1.ClusterSyncWindow, tenant *cattagev1beta1.Tenant) bool {
+	if slices.Contains(csw.Spec.Tenants, tenant.Name) {
+		return true
+	}
+	if csw.Spec.TenantSelector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(csw.Spec.TenantSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(tenant.Labels))
+}
+
+// updateSyncWindowStatus updates the status of the SyncWindow resources that are changed.
+// syncErr is the error that prevented the sync windows from being reflected to the AppProject, if any.
+// It returns the earliest time when one of the sync windows opens or closes, or zero if there is none.
+func (r *TenantReconciler) updateSyncWindowStatus(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, resources []cattagev1beta1.SyncWindow, syncErr error) (time.Time, error) {
+	now := time.Now()
+	var next time.Time
+	errs := make([]error, 0)
+	metrics.SyncWindowActiveVec.DeletePartialMatch(prometheus.Labels{"tenant": tenant.Name})
+	for _, res := range resources {
+		status := r.syncWindowStatus(cfg, tenant, &res, syncErr, now)
+		for _, w := range status.Windows {
+			if w.NextTransitionTime != nil && (next.IsZero() || w.NextTransitionTime.Time.Before(next)) {
+				next = w.NextTransitionTime.Time
+			}
+		}
+		setSyncWindowMetrics(tenant, &res, status)
+		if equality.Semantic.DeepEqual(status, &res.Status) {
+			continue
+		}
+		res.Status = *status
+		err := r.client.Status().Update(ctx, &res)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if meta.IsStatusConditionTrue(res.Status.Conditions, cattagev1beta1.ConditionSynced) {
+			r.recorder.Eventf(&res, corev1.EventTypeNormal, EventSyncWindowsReflected, "Reflected to AppProject %s/%s", cfg.ArgoCD.Namespace, tenant.Name)
+		}
+	}
+	if len(errs) > 0 {
+		return next, fmt.Errorf("failed to update sync window status: %v", errs)
+	}
+	return next, nil
+}
+
+// reportSyncWindowFailure marks the SyncWindow resources of the tenant as not synced.
+// This is used when the AppProject cannot be rendered, so errors are only logged.
+func (r *TenantReconciler) reportSyncWindowFailure(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, syncErr error) {
+	logger := log.FromContext(ctx)
+
+	resources, _, err := r.getSyncWindows(ctx, tenant)
+	if err != nil {
+		logger.Error(err, "failed to get sync windows")
+		return
+	}
+	if _, err := r.updateSyncWindowStatus(ctx, cfg, tenant, resources, syncErr); err != nil {
+		logger.Error(err, "failed to update sync window status")
+	}
+}
+
+// syncWindowStatus returns the desired status of the SyncWindow resource.
+func (r *TenantReconciler) syncWindowStatus(cfg *config.Config, tenant *cattagev1beta1.Tenant, res *cattagev1beta1.SyncWindow, syncErr error, now time.Time) *cattagev1beta1.SyncWindowStatus {
+	status := res.Status.DeepCopy()
+	status.ObservedGeneration = res.Generation
+
+	cond := metav1.Condition{
+		Type:               cattagev1beta1.ConditionSynced,
+		Status:             metav1.ConditionTrue,
+		Reason:             cattagev1beta1.ReasonSynced,
+		ObservedGeneration: res.Generation,
+	}
+	errs := syncwindow.Validate(res)
+	if len(errs) != 0 {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = cattagev1beta1.ReasonInvalid
+		cond.Message = errs.ToAggregate().Error()
+	} else if syncErr != nil {
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = failureReason(syncErr)
+		cond.Message = syncErr.Error()
+	}
+	meta.SetStatusCondition(&status.Conditions, cond)
+
+	if tenant.Spec.ArgoCD.SyncWindowScope == cattagev1beta1.SyncWindowScopeNamespace {
+		_, narrowed, dropped := scopeSyncWindows(res.Namespace, res.Spec.SyncWindows)
+		setNarrowedCondition(status, res, narrowed, dropped)
+	} else {
+		meta.RemoveStatusCondition(&status.Conditions, cattagev1beta1.ConditionNarrowed)
+	}
+
+	if cond.Status == metav1.ConditionTrue {
+		status.AppProject = &cattagev1beta1.ObjectReference{
+			Namespace: cfg.ArgoCD.Namespace,
+			Name:      tenant.Name,
+		}
+		// The time is updated only when the reflected sync windows may be changed.
+		if status.LastSyncTime == nil || !equality.Semantic.DeepEqual(status, &res.Status) {
+			status.LastSyncTime = ptr.To(metav1.NewTime(now))
+		}
+	}
+
+	// The states of the windows change over time, so they must not affect LastSyncTime.
+	status.Windows = nil
+	if len(errs) == 0 {
+		status.Windows = syncWindowStates(res.Spec.SyncWindows, now)
+	}
+	return status
+}
+
+// syncWindowStates returns whether each sync window is active at now and when it opens or closes next.
+func syncWindowStates(windows cattagev1beta1.SyncWindows, now time.Time) []cattagev1beta1.SyncWindowState {
+	states := make([]cattagev1beta1.SyncWindowState, 0, len(windows))
+	for _, w := range windows {
+		if w == nil {
+			continue
+		}
+		state := cattagev1beta1.SyncWindowState{Kind: w.Kind}
+		active, next, err := syncwindow.State(w, now)
+		if err == nil {
+			state.Active = active
+			if !next.IsZero() {
+				state.NextTransitionTime = ptr.To(metav1.NewTime(next.Truncate(time.Second)))
+			}
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// setSyncWindowMetrics records the number of active sync windows of each kind in the resource.
+func setSyncWindowMetrics(tenant *cattagev1beta1.Tenant, res *cattagev1beta1.SyncWindow, status *cattagev1beta1.SyncWindowStatus) {
+	for _, w := range status.Windows {
+		gauge := metrics.SyncWindowActiveVec.WithLabelValues(tenant.Name, res.Namespace, res.Name, w.Kind)
+		if w.Active {
+			gauge.Inc()
+		} else {
+			gauge.Add(0)
+		}
+	}
+}
+
+// setNarrowedCondition records how the sync windows of the resource are narrowed.
+func setNarrowedCondition(status *cattagev1beta1.SyncWindowStatus, res *cattagev1beta1.SyncWindow, narrowed, dropped int) {
+	cond := metav1.Condition{
+		Type:               cattagev1beta1.ConditionNarrowed,
+		Status:             metav1.ConditionFalse,
+		Reason:             cattagev1beta1.ReasonNotNarrowed,
+		ObservedGeneration: res.Generation,
+	}
+	switch {
+	case dropped != 0:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = cattagev1beta1.ReasonEntriesDropped
+		cond.Message = fmt.Sprintf("%d sync windows are dropped and %d are narrowed because they do not apply to namespace %s", dropped, narrowed, res.Namespace)
+	case narrowed != 0:
+		cond.Status = metav1.ConditionTrue
+		cond.Reason = cattagev1beta1.ReasonEntriesNarrowed
+		cond.Message = fmt.Sprintf("%d sync windows are narrowed to namespace %s", narrowed, res.Namespace)
+	}
+	meta.SetStatusCondition(&status.Conditions, cond)
+}
@@ -6,9 +6,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path"
 	"slices"
-	"strings"
 	"text/template"
+	"time"
 
 	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
 	"github.com/cybozu-go/cattage/internal/accurate"
@@ -17,55 +18,90 @@ import (
 	"github.com/cybozu-go/cattage/internal/config"
 	"github.com/cybozu-go/cattage/internal/constants"
 	"github.com/cybozu-go/cattage/internal/metrics"
+	"github.com/cybozu-go/cattage/internal/syncwindow"
+	"github.com/prometheus/client_golang/prometheus"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/apimachinery/pkg/types"
-	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	accorev1 "k8s.io/client-go/applyconfigurations/core/v1"
 	acrbacv1 "k8s.io/client-go/applyconfigurations/rbac/v1"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
-	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
-func NewTenantReconciler(client client.Client, config *config.Config) *TenantReconciler {
+// NewTenantReconciler creates TenantReconciler.
+// apiReader is used to read the resources created from `namespace.resourceTemplates`,
+// which are of arbitrary kinds and should not be cached.
+func NewTenantReconciler(client client.Client, apiReader client.Reader, recorder record.EventRecorder, config *config.Holder) *TenantReconciler {
 	return &TenantReconciler{
-		client: client,
-		config: config,
+		client:    client,
+		apiReader: apiReader,
+		recorder:  recorder,
+		config:    config,
 	}
 }
 
 // TenantReconciler reconciles a Tenant object
 type TenantReconciler struct {
-	client client.Client
-	config *config.Config
+	client    client.Client
+	apiReader client.Reader
+	recorder  record.EventRecorder
+	config    *config.Holder
 }
 
+// Reasons of the events recorded by TenantReconciler.
+// The reasons of failures are the same as those of the status conditions.
+const (
+	EventNamespaceAdopted     = "NamespaceAdopted"
+	EventNamespaceDisowned    = "NamespaceDisowned"
+	EventRoleBindingPatched   = "RoleBindingPatched"
+	EventRoleBindingDeleted   = "RoleBindingDeleted"
+	EventAppProjectPatched    = "AppProjectPatched"
+	EventAppProjectDeleted    = "AppProjectDeleted"
+	EventSyncWindowsReflected = "SyncWindowsReflected"
+
+	EventControllerMigrationStarted   = "ControllerMigrationStarted"
+	EventControllerMigrationCompleted = "ControllerMigrationCompleted"
+	EventControllerMigrationCancelled = "ControllerMigrationCancelled"
+)
+
 //+kubebuilder:rbac:groups=cattage.cybozu.io,resources=tenants,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cattage.cybozu.io,resources=tenants/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=cattage.cybozu.io,resources=tenants/finalizers,verbs=update
 //+kubebuilder:rbac:groups=cattage.cybozu.io,resources=syncwindows,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cattage.cybozu.io,resources=syncwindows/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=cattage.cybozu.io,resources=syncwindows/finalizers,verbs=update
+//+kubebuilder:rbac:groups=cattage.cybozu.io,resources=clustersyncwindows,verbs=get;list;watch
 //+kubebuilder:rbac:groups=argoproj.io,resources=appprojects,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=resourcequotas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=limitranges,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=get;list;watch;escalate;bind
 //+kubebuilder:rbac:groups=argoproj.io,resources=applications,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;update;patch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+// Secrets are written only in the namespace of Argo CD, which is allowed by the Role in the Helm chart.
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -78,8 +114,9 @@ type TenantReconciler struct {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.10.0/pkg/reconcile
 func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
 	logger := log.FromContext(ctx)
+	cfg := r.config.Get()
 
-	needRequeue, err := r.migrateToArgoCD25(ctx)
+	needRequeue, err := r.migrateToArgoCD25(ctx, cfg)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -93,7 +130,7 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 	}
 
 	if tenant.DeletionTimestamp != nil {
-		if err := r.finalize(ctx, tenant); err != nil {
+		if err := r.finalize(ctx, cfg, tenant); err != nil {
 			return ctrl.Result{}, fmt.Errorf("failed to finalize: %w", err)
 		}
 		return ctrl.Result{}, nil
@@ -110,41 +147,52 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 		r.setMetrics(tenant)
 	}(tenant.Status)
 
-	err = r.reconcileNamespaces(ctx, tenant)
+	// Status is recorded on the original tenant, while the resources are rendered from the resolved one.
+	resolved, err := r.resolveTenant(ctx, cfg, tenant)
 	if err != nil {
-		tenant.Status.Health = cattagev1beta1.TenantUnhealthy
-		meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
-			Type:    cattagev1beta1.ConditionReady,
-			Status:  metav1.ConditionFalse,
-			Reason:  "Failed",
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionNamespacesReady, err)
 	}
 
-	err = r.reconcileArgoCD(ctx, tenant)
+	err = r.reconcileNamespaces(ctx, cfg, resolved)
 	if err != nil {
-		tenant.Status.Health = cattagev1beta1.TenantUnhealthy
-		meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
-			Type:    cattagev1beta1.ConditionReady,
-			Status:  metav1.ConditionFalse,
-			Reason:  "Failed",
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionNamespacesReady, err)
 	}
 
-	err = r.reconcileConfigMapForApplicationController(ctx, tenant)
+	err = r.reconcileIsolation(ctx, cfg, resolved)
 	if err != nil {
-		tenant.Status.Health = cattagev1beta1.TenantUnhealthy
-		meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
-			Type:    cattagev1beta1.ConditionReady,
-			Status:  metav1.ConditionFalse,
-			Reason:  "Failed",
-			Message: err.Error(),
-		})
-		return ctrl.Result{}, err
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionNamespacesReady, err)
+	}
+
+	err = r.updateNamespacesStatus(ctx, tenant, resolved.Spec.Delegates)
+	if err != nil {
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionNamespacesReady, err)
 	}
+	setReconciledCondition(tenant, cattagev1beta1.ConditionNamespacesReady)
+
+	nextTransition, err := r.reconcileArgoCD(ctx, cfg, resolved)
+	if err != nil {
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionAppProjectReady, err)
+	}
+	err = r.reconcileRepositoryCredentials(ctx, cfg, resolved)
+	if err != nil {
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionAppProjectReady, err)
+	}
+	tenant.Status.AppProject = &cattagev1beta1.ObjectReference{
+		Namespace: cfg.ArgoCD.Namespace,
+		Name:      tenant.Name,
+	}
+	setReconciledCondition(tenant, cattagev1beta1.ConditionAppProjectReady)
+	setReconciledCondition(tenant, cattagev1beta1.ConditionSyncWindowsReady)
+
+	migrationWait, err := r.reconcileControllerAssignment(ctx, cfg, tenant, resolved)
+	if err != nil {
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionConfigMapReady, err)
+	}
+	tenant.Status.ConfigMap, err = tenantConfigMap(cfg, tenant)
+	if err != nil {
+		return ctrl.Result{}, r.setFailedCondition(tenant, cattagev1beta1.ConditionConfigMapReady, err)
+	}
+	setReconciledCondition(tenant, cattagev1beta1.ConditionConfigMapReady)
 
 	tenant.Status.Health = cattagev1beta1.TenantHealthy
 	meta.SetStatusCondition(&tenant.Status.Conditions, metav1.Condition{
@@ -154,12 +202,27 @@ func (r *TenantReconciler) Reconcile(ctx context.Context, req ctrl.Request) (res
 	})
 	logger.Info("Tenant successfully reconciled")
 
-	return ctrl.Result{}, nil
+	// Requeue when the migration between application controllers can be finished,
+	// and when a sync window opens or closes to keep the status of the SyncWindow resources up to date.
+	// The Secrets referenced by the repository credentials and the resources created from the resource templates
+	// are not watched, so they are read again periodically.
+	result = ctrl.Result{RequeueAfter: migrationWait}
+	if !nextTransition.IsZero() {
+		if d := time.Until(nextTransition) + time.Second; result.RequeueAfter == 0 || d < result.RequeueAfter {
+			result.RequeueAfter = d
+		}
+	}
+	if (len(resolved.Spec.ArgoCD.RepositoryCredentials) != 0 && cfg.ArgoCD.Repositories.CredentialTemplate != "") || len(cfg.Namespace.ResourceTemplates) != 0 {
+		if result.RequeueAfter == 0 || resyncPeriod < result.RequeueAfter {
+			result.RequeueAfter = resyncPeriod
+		}
+	}
+	return result, nil
 }
 
-func (r *TenantReconciler) migrateToArgoCD25(ctx context.Context) (bool /* needRequeue */, error) {
+func (r *TenantReconciler) migrateToArgoCD25(ctx context.Context, cfg *config.Config) (bool /* needRequeue */, error) {
 	apps := argocd.ApplicationList()
-	if err := r.client.List(ctx, apps, client.HasLabels{constants.OwnerAppNamespace}, client.InNamespace(r.config.ArgoCD.Namespace)); err != nil {
+	if err := r.client.List(ctx, apps, client.HasLabels{constants.OwnerAppNamespace}, client.InNamespace(cfg.ArgoCD.Namespace)); err != nil {
 		return false, fmt.Errorf("failed to list applications: %w", err)
 	}
 	if len(apps.Items) == 0 {
@@ -206,22 +269,25 @@ func containNamespace(roots []cattagev1beta1.RootNamespaceSpec, ns corev1.Namesp
 	return false
 }
 
-func (r *TenantReconciler) disownNamespace(ctx context.Context, ns *corev1.Namespace) error {
+func (r *TenantReconciler) disownNamespace(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, ns *corev1.Namespace) error {
 	managed, err := accorev1.ExtractNamespace(ns, constants.TenantFieldManager)
 	if err != nil {
 		return err
 	}
 	delete(managed.Labels, constants.OwnerTenant)
-	for k := range r.config.Namespace.CommonLabels {
+	for k := range cfg.Namespace.CommonLabels {
 		delete(managed.Labels, k)
 	}
-	for k := range r.config.Namespace.CommonAnnotations {
+	for k := range cfg.Namespace.CommonAnnotations {
 		delete(managed.Annotations, k)
 	}
+	delete(managed.Annotations, constants.ResourcesAnnotation)
 	err = r.patchNamespace(ctx, managed)
 	if err != nil {
 		return err
 	}
+	r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventNamespaceDisowned, "Disowned namespace %s", ns.Name)
+	r.recorder.Eventf(ns, corev1.EventTypeNormal, EventNamespaceDisowned, "Disowned by tenant %s", tenant.Name)
 	return nil
 }
 
@@ -247,13 +313,64 @@ func (r *TenantReconciler) removeRoleBinding(ctx context.Context, tenant *cattag
 		return err
 	}
 	logger.Info("RoleBinding deleted", "rolebinding", rb.Name)
+	r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventRoleBindingDeleted, "Deleted RoleBinding %s/%s", rb.Namespace, rb.Name)
+	return nil
+}
+
+func (r *TenantReconciler) removeResourceQuota(ctx context.Context, tenant *cattagev1beta1.Tenant, namespace string) error {
+	logger := log.FromContext(ctx)
+	quota := &corev1.ResourceQuota{}
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: tenant.Name + "-quota"}, quota)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if quota.DeletionTimestamp != nil {
+		return nil
+	}
+	labels := quota.GetLabels()
+	if labels == nil || labels[constants.OwnerTenant] != tenant.Name {
+		return nil
+	}
+	err = r.client.Delete(ctx, quota)
+	if err != nil {
+		return err
+	}
+	logger.Info("ResourceQuota deleted", "resourcequota", quota.Name)
+	return nil
+}
+
+func (r *TenantReconciler) removeLimitRange(ctx context.Context, tenant *cattagev1beta1.Tenant, namespace string) error {
+	logger := log.FromContext(ctx)
+	lr := &corev1.LimitRange{}
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: namespace, Name: tenant.Name + "-limitrange"}, lr)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if lr.DeletionTimestamp != nil {
+		return nil
+	}
+	labels := lr.GetLabels()
+	if labels == nil || labels[constants.OwnerTenant] != tenant.Name {
+		return nil
+	}
+	err = r.client.Delete(ctx, lr)
+	if err != nil {
+		return err
+	}
+	logger.Info("LimitRange deleted", "limitrange", lr.Name)
 	return nil
 }
 
-func (r *TenantReconciler) removeAppProject(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
+func (r *TenantReconciler) removeAppProject(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) error {
 	logger := log.FromContext(ctx)
 	proj := argocd.AppProject()
-	err := r.client.Get(ctx, client.ObjectKey{Namespace: r.config.ArgoCD.Namespace, Name: tenant.Name}, proj)
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: cfg.ArgoCD.Namespace, Name: tenant.Name}, proj)
 	if apierrors.IsNotFound(err) {
 		return nil
 	}
@@ -272,10 +389,11 @@ func (r *TenantReconciler) removeAppProject(ctx context.Context, tenant *cattage
 		return err
 	}
 	logger.Info("AppProject deleted", "project", proj.GetName())
+	r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventAppProjectDeleted, "Deleted AppProject %s/%s", proj.GetNamespace(), proj.GetName())
 	return nil
 }
 
-func (r *TenantReconciler) finalize(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
+func (r *TenantReconciler) finalize(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) error {
 	logger := log.FromContext(ctx)
 	if !controllerutil.ContainsFinalizer(tenant, constants.Finalizer) {
 		return nil
@@ -286,7 +404,7 @@ func (r *TenantReconciler) finalize(ctx context.Context, tenant *cattagev1beta1.
 		return fmt.Errorf("failed to list namespaces: %w", err)
 	}
 	for _, ns := range nss.Items {
-		err := r.disownNamespace(ctx, &ns)
+		err := r.disownNamespace(ctx, cfg, tenant, &ns)
 		if err != nil {
 			return err
 		}
@@ -294,8 +412,28 @@ func (r *TenantReconciler) finalize(ctx context.Context, tenant *cattagev1beta1.
 		if err != nil {
 			return err
 		}
+		err = r.removeResourceQuota(ctx, tenant, ns.Name)
+		if err != nil {
+			return err
+		}
+		err = r.removeLimitRange(ctx, tenant, ns.Name)
+		if err != nil {
+			return err
+		}
+		err = r.removeResources(ctx, tenant, &ns)
+		if err != nil {
+			return err
+		}
 	}
-	err := r.removeAppProject(ctx, tenant)
+	err := r.removeIsolation(ctx, tenant)
+	if err != nil {
+		return err
+	}
+	err = r.removeAppProject(ctx, cfg, tenant)
+	if err != nil {
+		return err
+	}
+	err = r.removeRepositoryCredentials(ctx, cfg, tenant)
 	if err != nil {
 		return err
 	}
@@ -311,6 +449,15 @@ func (r *TenantReconciler) finalize(ctx context.Context, tenant *cattagev1beta1.
 	return nil
 }
 
+func (r *TenantReconciler) recordAdoption(ctx context.Context, tenant *cattagev1beta1.Tenant, name string) {
+	r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventNamespaceAdopted, "Adopted namespace %s", name)
+	ns := &corev1.Namespace{}
+	if err := r.client.Get(ctx, client.ObjectKey{Name: name}, ns); err != nil {
+		return
+	}
+	r.recorder.Eventf(ns, corev1.EventTypeNormal, EventNamespaceAdopted, "Adopted by tenant %s", tenant.Name)
+}
+
 func (r *TenantReconciler) patchNamespace(ctx context.Context, ns *accorev1.NamespaceApplyConfiguration) error {
 	logger := log.FromContext(ctx)
 	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ns)
@@ -343,7 +490,7 @@ func (r *TenantReconciler) patchNamespace(ctx context.Context, ns *accorev1.Name
 	})
 }
 
-func (r *TenantReconciler) patchRoleBinding(ctx context.Context, rb *acrbacv1.RoleBindingApplyConfiguration) error {
+func (r *TenantReconciler) patchRoleBinding(ctx context.Context, tenant *cattagev1beta1.Tenant, rb *acrbacv1.RoleBindingApplyConfiguration) error {
 	logger := log.FromContext(ctx)
 	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(rb)
 	if err != nil {
@@ -369,25 +516,97 @@ func (r *TenantReconciler) patchRoleBinding(ctx context.Context, rb *acrbacv1.Ro
 	}
 
 	logger.Info("patching RoleBinding", "rolebinding", rb, "managed", managed)
+	err = r.client.Patch(ctx, patch, client.Apply, &client.PatchOptions{
+		FieldManager: constants.TenantFieldManager,
+		Force:        ptr.To(true),
+	})
+	if err != nil {
+		return err
+	}
+	r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventRoleBindingPatched, "Patched RoleBinding %s/%s", *rb.Namespace, *rb.Name)
+	return nil
+}
+
+func (r *TenantReconciler) patchResourceQuota(ctx context.Context, quota *accorev1.ResourceQuotaApplyConfiguration) error {
+	logger := log.FromContext(ctx)
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(quota)
+	if err != nil {
+		return err
+	}
+	patch := &unstructured.Unstructured{
+		Object: obj,
+	}
+
+	var orig corev1.ResourceQuota
+	err = r.client.Get(ctx, client.ObjectKey{Namespace: *quota.Namespace, Name: *quota.Name}, &orig)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	managed, err := accorev1.ExtractResourceQuota(&orig, constants.TenantFieldManager)
+	if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(quota, managed) {
+		return nil
+	}
+
+	logger.Info("patching ResourceQuota", "resourcequota", quota, "managed", managed)
 	return r.client.Patch(ctx, patch, client.Apply, &client.PatchOptions{
 		FieldManager: constants.TenantFieldManager,
 		Force:        ptr.To(true),
 	})
 }
 
-func (r *TenantReconciler) rolesMap(ctx context.Context, delegates []cattagev1beta1.DelegateSpec) (map[string][]Role, error) {
+func (r *TenantReconciler) patchLimitRange(ctx context.Context, lr *accorev1.LimitRangeApplyConfiguration) error {
+	logger := log.FromContext(ctx)
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(lr)
+	if err != nil {
+		return err
+	}
+	patch := &unstructured.Unstructured{
+		Object: obj,
+	}
+
+	var orig corev1.LimitRange
+	err = r.client.Get(ctx, client.ObjectKey{Namespace: *lr.Namespace, Name: *lr.Name}, &orig)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	managed, err := accorev1.ExtractLimitRange(&orig, constants.TenantFieldManager)
+	if err != nil {
+		return err
+	}
+
+	if equality.Semantic.DeepEqual(lr, managed) {
+		return nil
+	}
+
+	logger.Info("patching LimitRange", "limitrange", lr, "managed", managed)
+	return r.client.Patch(ctx, patch, client.Apply, &client.PatchOptions{
+		FieldManager: constants.TenantFieldManager,
+		Force:        ptr.To(true),
+	})
+}
+
+func (r *TenantReconciler) rolesMap(ctx context.Context, cfg *config.Config, delegates []cattagev1beta1.DelegateSpec) (map[string][]Role, error) {
 	result := make(map[string][]Role)
 
 	for _, d := range delegates {
 		delegatedTenant := &cattagev1beta1.Tenant{}
 		err := r.client.Get(ctx, client.ObjectKey{Name: d.Name}, delegatedTenant)
+		if apierrors.IsNotFound(err) {
+			return nil, withReason(cattagev1beta1.ReasonDelegateNotFound, fmt.Errorf("delegated tenant %s is not found: %w", d.Name, err))
+		}
 		if err != nil {
 			return nil, err
 		}
 		for _, role := range d.Roles {
 			result[role] = append(result[role], Role{
 				Name:        delegatedTenant.Name,
-				ExtraParams: delegatedTenant.Spec.ExtraParams.ToMap(),
+				ExtraParams: cfg.DefaultExtraParams(delegatedTenant.Spec.ExtraParams.ToMap()),
 			})
 		}
 	}
@@ -399,71 +618,120 @@ func (r *TenantReconciler) rolesMap(ctx context.Context, delegates []cattagev1be
 	return result, nil
 }
 
-func (r *TenantReconciler) reconcileNamespaces(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
+func (r *TenantReconciler) namespaceApplyConfiguration(cfg *config.Config, tenant *cattagev1beta1.Tenant, ns cattagev1beta1.RootNamespaceSpec, resources []resourceReference) (*accorev1.NamespaceApplyConfiguration, error) {
+	namespace := accorev1.Namespace(ns.Name)
+	labels := make(map[string]string)
+	for k, v := range cfg.Namespace.CommonLabels {
+		labels[k] = v
+	}
+	for k, v := range ns.Labels {
+		labels[k] = v
+	}
+	labels[accurate.LabelType] = accurate.NSTypeRoot
+	labels[constants.OwnerTenant] = tenant.Name
+	namespace.WithLabels(labels)
+	annotations := make(map[string]string)
+	for k, v := range cfg.Namespace.CommonAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range ns.Annotations {
+		annotations[k] = v
+	}
+	if len(resources) != 0 {
+		val, err := encodeResourceReferences(resources)
+		if err != nil {
+			return nil, err
+		}
+		annotations[constants.ResourcesAnnotation] = val
+	}
+	namespace.WithAnnotations(annotations)
+	return namespace, nil
+}
+
+func (r *TenantReconciler) reconcileNamespaces(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) error {
+	params, err := r.namespaceTemplateParams(ctx, cfg, tenant)
+	if err != nil {
+		return err
+	}
+
 	for _, ns := range tenant.Spec.RootNamespaces {
-		namespace := accorev1.Namespace(ns.Name)
-		labels := make(map[string]string)
-		for k, v := range r.config.Namespace.CommonLabels {
-			labels[k] = v
-		}
-		for k, v := range ns.Labels {
-			labels[k] = v
-		}
-		labels[accurate.LabelType] = accurate.NSTypeRoot
-		labels[constants.OwnerTenant] = tenant.Name
-		namespace.WithLabels(labels)
-		annotations := make(map[string]string)
-		for k, v := range r.config.Namespace.CommonAnnotations {
-			annotations[k] = v
-		}
-		for k, v := range ns.Annotations {
-			annotations[k] = v
-		}
-		namespace.WithAnnotations(annotations)
-		err := r.patchNamespace(ctx, namespace)
+		resources, err := r.renderResources(cfg, tenant, ns.Name, params)
 		if err != nil {
 			return err
 		}
+		desired := make([]resourceReference, len(resources))
+		for i, res := range resources {
+			desired[i] = referenceOf(res)
+		}
+		orig := &corev1.Namespace{}
+		err = r.client.Get(ctx, client.ObjectKey{Name: ns.Name}, orig)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		applied, err := appliedResources(orig)
+		if err != nil {
+			return err
+		}
+		stale := make([]resourceReference, 0)
+		for _, ref := range applied {
+			if !slices.Contains(desired, ref) {
+				stale = append(stale, ref)
+			}
+		}
 
-		tpl, err := template.New("RoleBinding Template").Parse(r.config.Namespace.RoleBindingTemplate)
+		// Stale resources are kept in the annotation until they are actually deleted.
+		namespace, err := r.namespaceApplyConfiguration(cfg, tenant, ns, mergeResourceReferences(desired, stale))
 		if err != nil {
 			return err
 		}
-		roles, err := r.rolesMap(ctx, tenant.Spec.Delegates)
+		err = r.patchNamespace(ctx, namespace)
 		if err != nil {
 			return err
 		}
+		if orig.Labels[constants.OwnerTenant] != tenant.Name {
+			r.recordAdoption(ctx, tenant, ns.Name)
+		}
 
-		var buf bytes.Buffer
-		err = tpl.Execute(&buf, struct {
-			Name        string
-			Roles       map[string][]Role
-			ExtraParams map[string]interface{}
-		}{
-			Name:        tenant.Name,
-			Roles:       roles,
-			ExtraParams: tenant.Spec.ExtraParams.ToMap(),
-		})
+		rb, err := r.renderRoleBinding(cfg, tenant, ns.Name, params)
 		if err != nil {
 			return err
 		}
-
-		rb := acrbacv1.RoleBinding(tenant.Name+"-admin", ns.Name)
-		err = k8syaml.Unmarshal(buf.Bytes(), rb)
+		err = r.patchRoleBinding(ctx, tenant, rb)
 		if err != nil {
 			return err
 		}
-		rb.WithLabels(map[string]string{
-			constants.OwnerTenant: tenant.Name,
-		})
-		rb.WithAnnotations(map[string]string{
-			accurate.AnnPropagate: accurate.PropagateUpdate,
-		})
 
-		err = r.patchRoleBinding(ctx, rb)
+		err = r.reconcileResourceQuota(ctx, cfg, tenant, ns.Name, params)
+		if err != nil {
+			return err
+		}
+		err = r.reconcileLimitRange(ctx, cfg, tenant, ns.Name, params)
 		if err != nil {
 			return err
 		}
+
+		for _, res := range resources {
+			err = r.patchResource(ctx, res)
+			if err != nil {
+				return err
+			}
+		}
+		if len(stale) != 0 {
+			for _, ref := range stale {
+				err = r.removeResource(ctx, tenant, ns.Name, ref)
+				if err != nil {
+					return err
+				}
+			}
+			namespace, err := r.namespaceApplyConfiguration(cfg, tenant, ns, desired)
+			if err != nil {
+				return err
+			}
+			err = r.patchNamespace(ctx, namespace)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	nss := &corev1.NamespaceList{}
 	if err := r.client.List(ctx, nss, client.MatchingFields{constants.RootNamespaceIndex: tenant.Name}); err != nil {
@@ -473,7 +741,7 @@ func (r *TenantReconciler) reconcileNamespaces(ctx context.Context, tenant *catt
 		if containNamespace(tenant.Spec.RootNamespaces, ns) {
 			continue
 		}
-		err := r.disownNamespace(ctx, &ns)
+		err := r.disownNamespace(ctx, cfg, tenant, &ns)
 		if err != nil {
 			return err
 		}
@@ -481,6 +749,18 @@ func (r *TenantReconciler) reconcileNamespaces(ctx context.Context, tenant *catt
 		if err != nil {
 			return err
 		}
+		err = r.removeResourceQuota(ctx, tenant, ns.Name)
+		if err != nil {
+			return err
+		}
+		err = r.removeLimitRange(ctx, tenant, ns.Name)
+		if err != nil {
+			return err
+		}
+		err = r.removeResources(ctx, tenant, &ns)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -491,124 +771,104 @@ type Role struct {
 	ExtraParams map[string]interface{}
 }
 
-func (r *TenantReconciler) reconcileArgoCD(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
-	logger := log.FromContext(ctx)
+// Destination is a cluster where the applications of a tenant can be deployed.
+type Destination struct {
+	Name       string
+	Server     string
+	Namespaces []string
+}
 
-	orig := argocd.AppProject()
-	err := r.client.Get(ctx, client.ObjectKey{Namespace: r.config.ArgoCD.Namespace, Name: tenant.Name}, orig)
-	if err != nil && !apierrors.IsNotFound(err) {
-		logger.Error(err, "failed to get AppProject")
-		return err
-	}
+// namespaceTemplateParams is the data passed to the templates of the resources created on root namespaces.
+type namespaceTemplateParams struct {
+	Name        string
+	Roles       map[string][]Role
+	ExtraParams map[string]interface{}
+}
 
-	tpl, err := template.New("AppProject Template").Parse(r.config.ArgoCD.AppProjectTemplate)
+func executeTemplate(cfg *config.Config, name, text string, data any) ([]byte, error) {
+	tpl, err := template.New(name).Option(cfg.TemplateOptions()...).Parse(text)
 	if err != nil {
-		return err
+		return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
 	}
-
-	namespaces, err := r.getTenantNamespaces(ctx, tenant)
+	var buf bytes.Buffer
+	err = tpl.Execute(&buf, data)
 	if err != nil {
-		return err
+		return nil, withReason(cattagev1beta1.ReasonInvalidTemplate, err)
 	}
+	return buf.Bytes(), nil
+}
 
-	roles, err := r.rolesMap(ctx, tenant.Spec.Delegates)
+func (r *TenantReconciler) reconcileResourceQuota(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string, params namespaceTemplateParams) error {
+	quota, err := r.renderResourceQuota(cfg, tenant, namespace, params)
 	if err != nil {
 		return err
 	}
-
-	repos := tenant.Spec.ArgoCD.Repositories
-	slices.Sort(repos)
-	params := tenant.Spec.ExtraParams.ToMap()
-
-	var buf bytes.Buffer
-	err = tpl.Execute(&buf, struct {
-		Name         string
-		Namespaces   []string
-		Roles        map[string][]Role
-		Repositories []string
-		ExtraParams  map[string]interface{}
-	}{
-		Name:         tenant.Name,
-		Namespaces:   namespaces,
-		Roles:        roles,
-		Repositories: repos,
-		ExtraParams:  params,
-	})
-	if err != nil {
-		return err
+	if quota == nil {
+		return r.removeResourceQuota(ctx, tenant, namespace)
 	}
+	return r.patchResourceQuota(ctx, quota)
+}
 
-	proj := argocd.AppProject()
-	dec := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
-	_, _, err = dec.Decode(buf.Bytes(), nil, proj)
+func (r *TenantReconciler) reconcileLimitRange(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant, namespace string, params namespaceTemplateParams) error {
+	lr, err := r.renderLimitRange(cfg, tenant, namespace, params)
 	if err != nil {
-		logger.Error(err, "failed to decode", "yaml", buf.String())
 		return err
 	}
-
-	proj.SetNamespace(r.config.ArgoCD.Namespace)
-	proj.SetName(tenant.Name)
-	proj.SetLabels(map[string]string{
-		constants.OwnerTenant: tenant.Name,
-	})
-	val, found, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "syncWindows")
-	if err != nil {
-		return err
+	if lr == nil {
+		return r.removeLimitRange(ctx, tenant, namespace)
 	}
-	var syncWindows cattagev1beta1.SyncWindows
-	if !found {
-		syncWindows = cattagev1beta1.SyncWindows{}
-	} else {
-		syncWindows, err = fromUnstructuredSlice[cattagev1beta1.SyncWindows](val)
-		if err != nil {
-			return err
-		}
+	return r.patchLimitRange(ctx, lr)
+}
+
+// reconcileArgoCD returns the earliest time when one of the sync windows of the tenant opens or closes.
+func (r *TenantReconciler) reconcileArgoCD(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) (time.Time, error) {
+	logger := log.FromContext(ctx)
+
+	orig := argocd.AppProject()
+	err := r.client.Get(ctx, client.ObjectKey{Namespace: cfg.ArgoCD.Namespace, Name: tenant.Name}, orig)
+	if err != nil && !apierrors.IsNotFound(err) {
+		logger.Error(err, "failed to get AppProject")
+		return time.Time{}, err
 	}
-	swResources, sws, err := r.getSyncWindows(ctx, tenant.Name)
+
+	proj, swResources, err := r.renderAppProject(ctx, cfg, tenant)
 	if err != nil {
-		return fmt.Errorf("failed to get sync windows: %w", err)
-	}
-	syncWindows = append(syncWindows, sws...)
-	if len(syncWindows) != 0 {
-		ret, err := toUnstructuredSlice[cattagev1beta1.SyncWindows](syncWindows)
-		if err != nil {
-			return err
-		}
-		err = unstructured.SetNestedSlice(proj.UnstructuredContent(), ret, "spec", "syncWindows")
-		if err != nil {
-			return err
-		}
+		logger.Error(err, "failed to render AppProject")
+		r.reportSyncWindowFailure(ctx, cfg, tenant, err)
+		return time.Time{}, err
 	}
 
 	managed, err := extract.ExtractManagedFields(orig, constants.TenantFieldManager)
 	if err != nil {
-		return err
-	}
-	if equality.Semantic.DeepEqual(proj, managed) && allSyncWindowsAreSynced(swResources) {
-		return nil
+		return time.Time{}, err
 	}
-
-	logger.Info("patching AppProject", "namespaces", namespaces, "roles", roles, "repositories", repos, "extraParams", params)
-	err = r.client.Patch(ctx, proj, client.Apply, &client.PatchOptions{
-		Force:        ptr.To(true),
-		FieldManager: constants.TenantFieldManager,
-	})
-	if err != nil {
-		logger.Error(err, "failed to patch AppProject")
-		return err
+	if !equality.Semantic.DeepEqual(proj, managed) {
+		logger.Info("patching AppProject", "project", proj.UnstructuredContent())
+		err = r.client.Patch(ctx, proj, client.Apply, &client.PatchOptions{
+			Force:        ptr.To(true),
+			FieldManager: constants.TenantFieldManager,
+		})
+		if err != nil {
+			logger.Error(err, "failed to patch AppProject")
+			if _, err2 := r.updateSyncWindowStatus(ctx, cfg, tenant, swResources, err); err2 != nil {
+				logger.Error(err2, "failed to update sync window status")
+			}
+			return time.Time{}, err
+		}
+		r.recorder.Eventf(tenant, corev1.EventTypeNormal, EventAppProjectPatched, "Patched AppProject %s/%s", cfg.ArgoCD.Namespace, tenant.Name)
 	}
 
-	err = r.updateSyncWindowStatus(ctx, swResources)
+	next, err := r.updateSyncWindowStatus(ctx, cfg, tenant, swResources, nil)
 	if err != nil {
-		return err
+		return time.Time{}, withCondition(cattagev1beta1.ConditionSyncWindowsReady, cattagev1beta1.ReasonStatusUpdateFailed, err)
 	}
 
 	logger.Info("AppProject successfully reconciled")
 
-	return nil
+	return next, nil
 }
 
-func (r *TenantReconciler) getTenantNamespaces(ctx context.Context, tenant *cattagev1beta1.Tenant) ([]string, error) {
+func (r *TenantReconciler) getTenantNamespaces(ctx context.Context, cfg *config.Config, tenant *cattagev1beta1.Tenant) ([]string, error) {
 	nss := &corev1.NamespaceList{}
 	if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenant.Name}); err != nil {
 		return nil, fmt.Errorf("failed to list namespaces: %w", err)
@@ -617,6 +877,13 @@ func (r *TenantReconciler) getTenantNamespaces(ctx context.Context, tenant *catt
 	for i, ns := range nss.Items {
 		namespaces[i] = ns.Name
 	}
+	if cfg.ArgoCD.CompactNamespaces && len(tenant.Spec.ArgoCD.NamespacePatterns) != 0 {
+		all := &corev1.NamespaceList{}
+		if err := r.client.List(ctx, all); err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		namespaces = compactNamespaces(tenant, namespaces, all.Items)
+	}
 	delegatedNamespaces, err := r.getDelegatedNamespaces(ctx, tenant.Spec.Delegates)
 	if err != nil {
 		return nil, err
@@ -626,9 +893,9 @@ func (r *TenantReconciler) getTenantNamespaces(ctx context.Context, tenant *catt
 	return namespaces, nil
 }
 
-func (r *TenantReconciler) getSyncWindows(ctx context.Context, tenantOwner string) ([]cattagev1beta1.SyncWindow, cattagev1beta1.SyncWindows, error) {
+func (r *TenantReconciler) getSyncWindows(ctx context.Context, tenant *cattagev1beta1.Tenant) ([]cattagev1beta1.SyncWindow, cattagev1beta1.SyncWindows, error) {
 	nss := &corev1.NamespaceList{}
-	if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenantOwner}); err != nil {
+	if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenant.Name}); err != nil {
 		return nil, nil, fmt.Errorf("failed to list namespaces: %w", err)
 	}
 
@@ -652,38 +919,20 @@ func (r *TenantReconciler) getSyncWindows(ctx context.Context, tenantOwner strin
 	syncWindows := cattagev1beta1.SyncWindows{}
 
 	for _, res := range resources {
-		syncWindows = append(syncWindows, res.Spec.SyncWindows...)
-	}
-
-	return resources, syncWindows, nil
-}
-
-func (r *TenantReconciler) updateSyncWindowStatus(ctx context.Context, resources []cattagev1beta1.SyncWindow) error {
-	errs := make([]error, 0)
-	for _, res := range resources {
-		meta.SetStatusCondition(&res.Status.Conditions, metav1.Condition{
-			Type:   cattagev1beta1.ConditionSynced,
-			Status: metav1.ConditionTrue,
-			Reason: "OK",
-		})
-		err := r.client.Status().Update(ctx, &res)
-		if err != nil {
-			errs = append(errs, err)
+		// Invalid sync windows are not reflected so that they do not break the AppProject.
+		// They are reported in the status of each resource.
+		if len(syncwindow.Validate(&res)) != 0 {
+			continue
 		}
-	}
-	if len(errs) > 0 {
-		return fmt.Errorf("failed to update sync window status: %v", errs)
-	}
-	return nil
-}
-
-func allSyncWindowsAreSynced(resources []cattagev1beta1.SyncWindow) bool {
-	for _, res := range resources {
-		if !meta.IsStatusConditionTrue(res.Status.Conditions, cattagev1beta1.ConditionSynced) {
-			return false
+		if tenant.Spec.ArgoCD.SyncWindowScope != cattagev1beta1.SyncWindowScopeNamespace {
+			syncWindows = append(syncWindows, res.Spec.SyncWindows...)
+			continue
 		}
+		scoped, _, _ := scopeSyncWindows(res.Namespace, res.Spec.SyncWindows)
+		syncWindows = append(syncWindows, scoped...)
 	}
-	return true
+
+	return resources, syncWindows, nil
 }
 
 func fromUnstructuredSlice[T any](data []interface{}) (T, error) {
@@ -721,167 +970,16 @@ func (r *TenantReconciler) getDelegatedNamespaces(ctx context.Context, delegates
 	return result, nil
 }
 
-func (r *TenantReconciler) reconcileConfigMapForApplicationController(ctx context.Context, tenant *cattagev1beta1.Tenant) error {
-	cmList := &corev1.ConfigMapList{}
-	err := r.client.List(ctx, cmList, client.MatchingLabels{constants.ManagedByLabel: "cattage"})
-	if err != nil {
-		return err
-	}
-	controllerNames := map[string]struct{}{}
-	for _, cm := range cmList.Items {
-		if cm.Labels[constants.ControllerNameLabel] != "" {
-			controllerNames[cm.Labels[constants.ControllerNameLabel]] = struct{}{}
-		}
-	}
-	controllerName := tenant.Spec.ControllerName
-	if controllerName == "" {
-		controllerName = constants.DefaultApplicationControllerName
-	}
-	controllerNames[controllerName] = struct{}{}
-
-	for name := range controllerNames {
-		err := r.updateConfigMap(ctx, name)
-		if err != nil {
-			return err
-		}
-	}
-
-	err = r.updateAllTenantNamespacesConfigMap(ctx)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *TenantReconciler) updateConfigMap(ctx context.Context, controllerName string) error {
-	logger := log.FromContext(ctx)
-
-	configMapName := controllerName + "-application-controller-cm"
-	cm := &corev1.ConfigMap{}
-	cm.Name = configMapName
-	cm.Namespace = r.config.ArgoCD.Namespace
-
-	tenantList := &cattagev1beta1.TenantList{}
-	if err := r.client.List(ctx, tenantList, client.MatchingFields{constants.ControllerNameIndex: controllerName}); err != nil {
-		return fmt.Errorf("failed to list tenants: %w", err)
-	}
-
-	tenants := tenantList.Items
-	slices.SortFunc(tenants, func(x, y cattagev1beta1.Tenant) int {
-		return cmp.Compare(x.Name, y.Name)
-	})
-
-	if len(tenants) == 0 {
-		err := r.client.Delete(ctx, cm)
-		return err
-	}
-
-	namespaces := make([]string, 0)
-	for _, t := range tenants {
-		nss := &corev1.NamespaceList{}
-		if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: t.Name}); err != nil {
-			return fmt.Errorf("failed to list namespaces: %w", err)
-		}
-		for _, ns := range nss.Items {
-			namespaces = append(namespaces, ns.Name)
-		}
-	}
-	slices.Sort(namespaces)
-
-	op, err := ctrl.CreateOrUpdate(ctx, r.client, cm, func() error {
-		cm.Labels = map[string]string{
-			constants.ManagedByLabel:      "cattage",
-			constants.PartOfLabel:         "argocd",
-			constants.ControllerNameLabel: controllerName,
-		}
-		cm.Data = map[string]string{
-			"application.namespaces": strings.Join(namespaces, ","),
-		}
-		cm.OwnerReferences = nil
-		for _, tenant := range tenants {
-			err := controllerutil.SetOwnerReference(&tenant, cm, r.client.Scheme())
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		logger.Error(err, "failed to update ConfigMap")
-		return err
-	}
-	if op != controllerutil.OperationResultNone {
-		tenantNames := make([]string, len(tenants))
-		for i, t := range tenants {
-			tenantNames[i] = t.Name
-		}
-		logger.Info("ConfigMap successfully reconciled", "namespaces", namespaces, "tenants", tenantNames)
-	}
-
-	return nil
-}
-
-func (r *TenantReconciler) updateAllTenantNamespacesConfigMap(ctx context.Context) error {
-	logger := log.FromContext(ctx)
-
-	configMapName := "all-tenant-namespaces-cm"
-	cm := &corev1.ConfigMap{}
-	cm.Name = configMapName
-	cm.Namespace = r.config.ArgoCD.Namespace
-
-	tenantList := &cattagev1beta1.TenantList{}
-	err := r.client.List(ctx, tenantList)
-	if err != nil {
-		return err
-	}
-	tenants := tenantList.Items
-	slices.SortFunc(tenants, func(x, y cattagev1beta1.Tenant) int {
-		return cmp.Compare(x.Name, y.Name)
-	})
-
-	allNamespaces := make([]string, 0)
-	for _, tenant := range tenants {
-		nss := &corev1.NamespaceList{}
-		if err := r.client.List(ctx, nss, client.MatchingFields{constants.TenantNamespaceIndex: tenant.Name}); err != nil {
-			return fmt.Errorf("failed to list namespaces: %w", err)
-		}
-		for _, ns := range nss.Items {
-			allNamespaces = append(allNamespaces, ns.Name)
-		}
-	}
-	slices.Sort(allNamespaces)
-
-	op, err := ctrl.CreateOrUpdate(ctx, r.client, cm, func() error {
-		cm.Labels = map[string]string{
-			constants.ManagedByLabel: "cattage",
-			constants.PartOfLabel:    "argocd",
-		}
-		cm.Data = map[string]string{
-			"application.namespaces": strings.Join(allNamespaces, ","),
-		}
-		cm.OwnerReferences = nil
-		for _, tenant := range tenants {
-			err := controllerutil.SetOwnerReference(&tenant, cm, r.client.Scheme())
-			if err != nil {
-				return err
-			}
-		}
-		return nil
-	})
-	if err != nil {
-		logger.Error(err, "failed to update ConfigMap")
-		return err
+// tenantControllerName returns the name of the application-controller that manages the applications of the tenant.
+// The assignment recorded in the status is used if any.
+func tenantControllerName(tenant *cattagev1beta1.Tenant) string {
+	if tenant.Status.ControllerName != "" {
+		return tenant.Status.ControllerName
 	}
-	if op != controllerutil.OperationResultNone {
-		tenantNames := make([]string, len(tenants))
-		for i, t := range tenants {
-			tenantNames[i] = t.Name
-		}
-		logger.Info("ConfigMap successfully reconciled", "allNamespaces", allNamespaces, "tenants", tenantNames)
+	if tenant.Spec.ControllerName != "" {
+		return tenant.Spec.ControllerName
 	}
-
-	return nil
+	return constants.DefaultApplicationControllerName
 }
 
 func (r *TenantReconciler) setMetrics(tenant *cattagev1beta1.Tenant) {
@@ -893,11 +991,18 @@ func (r *TenantReconciler) setMetrics(tenant *cattagev1beta1.Tenant) {
 		metrics.HealthyVec.WithLabelValues(tenant.Name).Set(0)
 		metrics.UnhealthyVec.WithLabelValues(tenant.Name).Set(1)
 	}
+	if tenant.Status.Migration != nil {
+		metrics.ControllerMigrationInProgressVec.WithLabelValues(tenant.Name).Set(1)
+	} else {
+		metrics.ControllerMigrationInProgressVec.WithLabelValues(tenant.Name).Set(0)
+	}
 }
 
 func (r *TenantReconciler) removeMetrics(tenant *cattagev1beta1.Tenant) {
 	metrics.HealthyVec.DeleteLabelValues(tenant.Name)
 	metrics.UnhealthyVec.DeleteLabelValues(tenant.Name)
+	metrics.ControllerMigrationInProgressVec.DeleteLabelValues(tenant.Name)
+	metrics.SyncWindowActiveVec.DeletePartialMatch(prometheus.Labels{"tenant": tenant.Name})
 }
 
 // SetupWithManager sets up the controller with the Manager.
@@ -924,17 +1029,110 @@ func (r *TenantReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		}
 		return []reconcile.Request{{NamespacedName: types.NamespacedName{Name: owner}}}
 	}
+	allTenantsHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		tenants := &cattagev1beta1.TenantList{}
+		if err := r.client.List(ctx, tenants); err != nil {
+			logger := log.FromContext(ctx)
+			logger.Error(err, "failed to list tenants")
+			return nil
+		}
+		requests := make([]reconcile.Request, len(tenants.Items))
+		for i, t := range tenants.Items {
+			requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: t.Name}}
+		}
+		return requests
+	}
+	// A namespace may stop the namespace patterns of other tenants from being used.
+	patternHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		if !r.config.Get().ArgoCD.CompactNamespaces {
+			return nil
+		}
+		tenants := &cattagev1beta1.TenantList{}
+		if err := r.client.List(ctx, tenants); err != nil {
+			logger := log.FromContext(ctx)
+			logger.Error(err, "failed to list tenants")
+			return nil
+		}
+		var requests []reconcile.Request
+		for _, t := range tenants.Items {
+			if slices.ContainsFunc(t.Spec.ArgoCD.NamespacePatterns, func(pattern string) bool {
+				ok, _ := path.Match(pattern, o.GetName())
+				return ok
+			}) {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Name: t.Name}})
+			}
+		}
+		return requests
+	}
+	descendantsHandler := func(ctx context.Context, o client.Object) []reconcile.Request {
+		names, err := r.descendants(ctx, o.GetName())
+		if err != nil {
+			logger := log.FromContext(ctx)
+			logger.Error(err, "failed to list descendant tenants", "tenant", o.GetName())
+			return nil
+		}
+		requests := make([]reconcile.Request, len(names))
+		for i, name := range names {
+			requests[i] = reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+		}
+		return requests
+	}
+
+	// Reconcile all tenants when the configurations are reloaded to roll out the new templates.
+	reloaded := make(chan event.GenericEvent, 1)
+	r.config.OnChange(func(*config.Config) {
+		select {
+		case reloaded <- event.GenericEvent{Object: &cattagev1beta1.Tenant{}}:
+		default:
+		}
+	})
 
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&cattagev1beta1.Tenant{}).
+		Watches(&cattagev1beta1.Tenant{}, handler.EnqueueRequestsFromMapFunc(descendantsHandler)).
 		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(tenantHandler)).
+		Watches(&corev1.Namespace{}, handler.EnqueueRequestsFromMapFunc(patternHandler)).
 		Watches(&rbacv1.RoleBinding{}, handler.EnqueueRequestsFromMapFunc(tenantHandler)).
+		Watches(&corev1.ResourceQuota{}, handler.EnqueueRequestsFromMapFunc(tenantHandler)).
+		Watches(&corev1.LimitRange{}, handler.EnqueueRequestsFromMapFunc(tenantHandler)).
+		Watches(&networkingv1.NetworkPolicy{}, handler.EnqueueRequestsFromMapFunc(tenantHandler)).
 		Watches(argocd.AppProject(), handler.EnqueueRequestsFromMapFunc(tenantHandler)).
+		Watches(&corev1.Secret{}, handler.EnqueueRequestsFromMapFunc(tenantHandler)).
 		Watches(&cattagev1beta1.SyncWindow{}, handler.EnqueueRequestsFromMapFunc(nsHandler)).
+		Watches(&cattagev1beta1.ClusterSyncWindow{}, handler.EnqueueRequestsFromMapFunc(allTenantsHandler)).
+		WatchesRawSource(source.Channel(reloaded, handler.EnqueueRequestsFromMapFunc(allTenantsHandler))).
 		Complete(r)
 }
 
-func SetupIndexForNamespace(ctx context.Context, mgr manager.Manager) error {
+// resyncPeriod is the interval to read the resources that are not watched again.
+const resyncPeriod = 10 * time.Minute
+
+// CacheOptions returns the options of the cache for TenantReconciler.
+// Only the Secrets owned by tenants are cached, so that the other Secrets in the cluster are not kept in memory.
+func CacheOptions() (cache.Options, error) {
+	selector, err := labels.Parse(constants.OwnerTenant)
+	if err != nil {
+		return cache.Options{}, err
+	}
+	return cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Secret{}: {Label: selector},
+		},
+	}, nil
+}
+
+// ClientOptions returns the options of the client for TenantReconciler.
+// Secrets are read from the API server because the Secrets referenced by tenants are not cached.
+func ClientOptions() client.Options {
+	return client.Options{
+		Cache: &client.CacheOptions{
+			Unstructured: true,
+			DisableFor:   []client.Object{&corev1.Secret{}},
+		},
+	}
+}
+
+func SetupIndexForNamespace(ctx context.Context, mgr cluster.Cluster) error {
 	ns := &corev1.Namespace{}
 	err := mgr.GetFieldIndexer().IndexField(ctx, ns, constants.RootNamespaceIndex, func(rawObj client.Object) []string {
 		nsType := rawObj.GetLabels()[accurate.LabelType]
@@ -963,12 +1161,23 @@ func SetupIndexForNamespace(ctx context.Context, mgr manager.Manager) error {
 	}
 
 	tenant := &cattagev1beta1.Tenant{}
-	return mgr.GetFieldIndexer().IndexField(ctx, tenant, constants.ControllerNameIndex, func(rawObj client.Object) []string {
+	err = mgr.GetFieldIndexer().IndexField(ctx, tenant, constants.ControllerNameIndex, func(rawObj client.Object) []string {
 		tenant := rawObj.(*cattagev1beta1.Tenant)
-		controllerName := tenant.Spec.ControllerName
-		if controllerName == "" {
-			return []string{constants.DefaultApplicationControllerName}
+		// The tenant is not assigned to any controller while migrating between controllers.
+		if tenant.Status.Migration != nil {
+			return nil
+		}
+		return []string{tenantControllerName(tenant)}
+	})
+	if err != nil {
+		return err
+	}
+
+	return mgr.GetFieldIndexer().IndexField(ctx, tenant, constants.ParentTenantIndex, func(rawObj client.Object) []string {
+		parent := rawObj.(*cattagev1beta1.Tenant).Spec.Parent
+		if parent == "" {
+			return nil
 		}
-		return []string{controllerName}
+		return []string{parent}
 	})
 }
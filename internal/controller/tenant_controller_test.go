@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"errors"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,12 +14,15 @@ import (
 	"github.com/cybozu-go/cattage/internal/argocd"
 	tenantconfig "github.com/cybozu-go/cattage/internal/config"
 	"github.com/cybozu-go/cattage/internal/constants"
+	"github.com/cybozu-go/cattage/internal/metrics"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	. "github.com/onsi/gomega/gstruct"
 	"github.com/prometheus/client_golang/prometheus/testutil"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -36,12 +40,24 @@ var appProjectTemplate string
 //go:embed testdata/rolebindingtemplate.yaml
 var roleBindingTemplate string
 
+//go:embed testdata/resourcequotatemplate.yaml
+var resourceQuotaTemplate string
+
+//go:embed testdata/limitrangetemplate.yaml
+var limitRangeTemplate string
+
+//go:embed testdata/serviceaccounttemplate.yaml
+var serviceAccountTemplate string
+
 var _ = Describe("Tenant controller", Ordered, func() {
 	ctx := context.Background()
 	var stopFunc func()
 	var tenantCfg *tenantconfig.Config
+	var tr *TenantReconciler
 
 	BeforeEach(func() {
+		cacheOpts, err := CacheOptions()
+		Expect(err).ToNot(HaveOccurred())
 		mgr, err := ctrl.NewManager(k8sCfg, ctrl.Options{
 			Scheme:         scheme,
 			LeaderElection: false,
@@ -51,11 +67,8 @@ var _ = Describe("Tenant controller", Ordered, func() {
 			Controller: config.Controller{
 				SkipNameValidation: ptr.To(true),
 			},
-			Client: client.Options{
-				Cache: &client.CacheOptions{
-					Unstructured: true,
-				},
-			},
+			Client: ClientOptions(),
+			Cache:  cacheOpts,
 		})
 		Expect(err).ToNot(HaveOccurred())
 
@@ -67,17 +80,31 @@ var _ = Describe("Tenant controller", Ordered, func() {
 				CommonAnnotations: map[string]string{
 					"hoge": "fuga",
 				},
-				RoleBindingTemplate: roleBindingTemplate,
+				RoleBindingTemplate:   roleBindingTemplate,
+				ResourceQuotaTemplate: resourceQuotaTemplate,
+				LimitRangeTemplate:    limitRangeTemplate,
+				ResourceTemplates: []tenantconfig.ResourceTemplate{
+					{
+						Name:     "deployer",
+						Template: serviceAccountTemplate,
+					},
+				},
 			},
 			ArgoCD: tenantconfig.ArgoCDConfig{
 				Namespace:                           "argocd",
 				AppProjectTemplate:                  appProjectTemplate,
 				PreventAppCreationInArgoCDNamespace: true,
 			},
+			Isolation: tenantconfig.IsolationConfig{
+				Enabled:           true,
+				AllowedNamespaces: []string{"argocd"},
+			},
 		}
-		tr := NewTenantReconciler(mgr.GetClient(), tenantCfg)
+		tr = NewTenantReconciler(mgr.GetClient(), mgr.GetAPIReader(), mgr.GetEventRecorderFor("cattage-controller"), tenantconfig.NewHolder(tenantCfg))
 		err = tr.SetupWithManager(mgr)
 		Expect(err).ToNot(HaveOccurred())
+		err = NewConfigMapReconciler(mgr.GetClient(), tr.config).SetupWithManager(mgr)
+		Expect(err).ToNot(HaveOccurred())
 		err = SetupIndexForNamespace(ctx, mgr)
 		Expect(err).ToNot(HaveOccurred())
 
@@ -854,6 +881,1435 @@ var _ = Describe("Tenant controller", Ordered, func() {
 		}).Should(Succeed())
 	})
 
+	It("should create resource quotas and limit ranges on root namespaces", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "q-team",
+				Finalizers: []string{constants.Finalizer},
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-q1"},
+					{Name: "app-q2"},
+				},
+				ExtraParams: &cattagev1beta1.Params{Data: map[string]interface{}{
+					"CPU": "4",
+				}},
+				LimitRangeTemplate: `apiVersion: v1
+kind: LimitRange
+spec:
+  limits:
+    - type: Container
+      default:
+        cpu: "1"
+`,
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, ns := range []string{"app-q1", "app-q2"} {
+			quota := &corev1.ResourceQuota{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, client.ObjectKey{Namespace: ns, Name: "q-team-quota"}, quota)
+			}).Should(Succeed())
+			Expect(quota.Labels).Should(HaveKeyWithValue(constants.OwnerTenant, "q-team"))
+			Expect(quota.Spec.Hard).Should(HaveKey(corev1.ResourceRequestsCPU))
+			cpu := quota.Spec.Hard[corev1.ResourceRequestsCPU]
+			Expect(cpu.String()).Should(Equal("4"))
+
+			lr := &corev1.LimitRange{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, client.ObjectKey{Namespace: ns, Name: "q-team-limitrange"}, lr)
+			}).Should(Succeed())
+			Expect(lr.Labels).Should(HaveKeyWithValue(constants.OwnerTenant, "q-team"))
+			Expect(lr.Spec.Limits).Should(HaveLen(1))
+			Expect(lr.Spec.Limits[0].Type).Should(Equal(corev1.LimitTypeContainer))
+			defaultCPU := lr.Spec.Limits[0].Default[corev1.ResourceCPU]
+			Expect(defaultCPU.String()).Should(Equal("1"))
+		}
+
+		By("removing app-q2")
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: tenant.Name}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.RootNamespaces = []cattagev1beta1.RootNamespaceSpec{
+			{Name: "app-q1"},
+		}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-q2", Name: "q-team-quota"}, &corev1.ResourceQuota{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-q2", Name: "q-team-limitrange"}, &corev1.LimitRange{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		}).Should(Succeed())
+
+		By("removing tenant")
+		err = k8sClient.Delete(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-q1", Name: "q-team-quota"}, &corev1.ResourceQuota{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-q1", Name: "q-team-limitrange"}, &corev1.LimitRange{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		}).Should(Succeed())
+	})
+
+	It("should create resources from resource templates", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:       "r-team",
+				Finalizers: []string{constants.Finalizer},
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-r"},
+				},
+				ExtraParams: &cattagev1beta1.Params{Data: map[string]interface{}{
+					"Deployer": "r-deployer",
+				}},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		sa := &corev1.ServiceAccount{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-r", Name: "r-deployer"}, sa)
+		}).Should(Succeed())
+		Expect(sa.Labels).Should(MatchAllKeys(Keys{
+			constants.OwnerTenant:           Equal("r-team"),
+			constants.ResourceTemplateLabel: Equal("deployer"),
+		}))
+
+		ns := &corev1.Namespace{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "app-r"}, ns)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ns.Annotations).Should(HaveKeyWithValue(constants.ResourcesAnnotation, `[{"apiVersion":"v1","kind":"ServiceAccount","name":"r-deployer"}]`))
+
+		By("rendering nothing")
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: tenant.Name}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.ExtraParams = nil
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-r", Name: "r-deployer"}, &corev1.ServiceAccount{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: "app-r"}, ns)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(ns.Annotations).ShouldNot(HaveKey(constants.ResourcesAnnotation))
+		}).Should(Succeed())
+
+		By("removing tenant")
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: tenant.Name}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.ExtraParams = &cattagev1beta1.Params{Data: map[string]interface{}{
+			"Deployer": "r-deployer",
+		}}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-r", Name: "r-deployer"}, sa)
+		}).Should(Succeed())
+
+		err = k8sClient.Delete(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-r", Name: "r-deployer"}, &corev1.ServiceAccount{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+			err = k8sClient.Get(ctx, client.ObjectKey{Name: "app-r"}, ns)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(ns.Annotations).ShouldNot(HaveKey(constants.ResourcesAnnotation))
+		}).Should(Succeed())
+	})
+
+	It("should isolate tenant namespaces with network policies", func() {
+		for _, ns := range []string{"app-x", "sub-4"} {
+			np := &networkingv1.NetworkPolicy{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, client.ObjectKey{Namespace: ns, Name: constants.IsolationNetworkPolicyName}, np)
+			}).Should(Succeed())
+			Expect(np.Labels).Should(HaveKeyWithValue(constants.OwnerTenant, "x-team"))
+			Expect(np.Spec.PolicyTypes).Should(ConsistOf(networkingv1.PolicyTypeIngress))
+			Expect(np.Spec.Ingress).Should(ConsistOf(
+				networkingv1.NetworkPolicyIngressRule{
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							NamespaceSelector: &metav1.LabelSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{
+									{
+										Key:      constants.OwnerTenant,
+										Operator: metav1.LabelSelectorOpIn,
+										Values:   []string{"c-team", "x-team"},
+									},
+								},
+							},
+						},
+					},
+				},
+				networkingv1.NetworkPolicyIngressRule{
+					From: []networkingv1.NetworkPolicyPeer{
+						{
+							NamespaceSelector: &metav1.LabelSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{
+									{
+										Key:      corev1.LabelMetadataName,
+										Operator: metav1.LabelSelectorOpIn,
+										Values:   []string{"argocd"},
+									},
+								},
+							},
+						},
+					},
+				},
+			))
+		}
+
+		By("adding a network peer")
+		tenant := &cattagev1beta1.Tenant{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.NetworkPeers = []string{"a-team"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			np := &networkingv1.NetworkPolicy{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-x", Name: constants.IsolationNetworkPolicyName}, np)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(np.Spec.Ingress).ShouldNot(BeEmpty())
+			g.Expect(np.Spec.Ingress[0].From[0].NamespaceSelector.MatchExpressions[0].Values).Should(Equal([]string{"a-team", "c-team", "x-team"}))
+		}).Should(Succeed())
+	})
+
+	It("should report owned namespaces and conditions in status", func() {
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.RootNamespaces).Should(Equal([]string{"app-x"}))
+			g.Expect(tenant.Status.Namespaces).Should(ContainElements("app-x", "sub-4"))
+			g.Expect(tenant.Status.DelegatedNamespaces).Should(ContainElement("app-c"))
+			g.Expect(tenant.Status.AppProject).Should(Equal(&cattagev1beta1.ObjectReference{Namespace: "argocd", Name: "x-team"}))
+			g.Expect(tenant.Status.ConfigMap).Should(Equal(&cattagev1beta1.ObjectReference{Namespace: "argocd", Name: "default-application-controller-cm"}))
+			for _, condType := range []string{
+				cattagev1beta1.ConditionReady,
+				cattagev1beta1.ConditionNamespacesReady,
+				cattagev1beta1.ConditionAppProjectReady,
+				cattagev1beta1.ConditionSyncWindowsReady,
+				cattagev1beta1.ConditionConfigMapReady,
+			} {
+				g.Expect(meta.IsStatusConditionTrue(tenant.Status.Conditions, condType)).Should(BeTrue(), condType)
+			}
+		}).Should(Succeed())
+
+		By("delegating to a tenant that does not exist")
+		sTeam := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "s-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-s"},
+				},
+				Delegates: []cattagev1beta1.DelegateSpec{
+					{
+						Name:  "no-such-team",
+						Roles: []string{"admin"},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, sTeam)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "s-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.Health).Should(Equal(cattagev1beta1.TenantUnhealthy))
+			cond := meta.FindStatusCondition(tenant.Status.Conditions, cattagev1beta1.ConditionNamespacesReady)
+			g.Expect(cond).ShouldNot(BeNil())
+			g.Expect(cond.Status).Should(Equal(metav1.ConditionFalse))
+			g.Expect(cond.Reason).Should(Equal(cattagev1beta1.ReasonDelegateNotFound))
+			g.Expect(meta.IsStatusConditionFalse(tenant.Status.Conditions, cattagev1beta1.ConditionReady)).Should(BeTrue())
+		}).Should(Succeed())
+
+		By("removing the delegate")
+		tenant := &cattagev1beta1.Tenant{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "s-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.Delegates = nil
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "s-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.Health).Should(Equal(cattagev1beta1.TenantHealthy))
+			g.Expect(tenant.Status.RootNamespaces).Should(Equal([]string{"app-s"}))
+			g.Expect(meta.IsStatusConditionTrue(tenant.Status.Conditions, cattagev1beta1.ConditionNamespacesReady)).Should(BeTrue())
+		}).Should(Succeed())
+	})
+
+	It("should record events", func() {
+		hasEvent := func(g Gomega, kind, name, eventType, reason string) {
+			events := &corev1.EventList{}
+			err := k8sClient.List(ctx, events)
+			g.Expect(err).ToNot(HaveOccurred())
+			found := false
+			for _, ev := range events.Items {
+				if ev.InvolvedObject.Kind == kind && ev.InvolvedObject.Name == name && ev.Type == eventType && ev.Reason == reason {
+					found = true
+					break
+				}
+			}
+			g.Expect(found).Should(BeTrue(), "%s event for %s %s is not found", reason, kind, name)
+		}
+
+		Eventually(func(g Gomega) {
+			hasEvent(g, "Tenant", "x-team", corev1.EventTypeNormal, EventNamespaceAdopted)
+			hasEvent(g, "Tenant", "x-team", corev1.EventTypeNormal, EventRoleBindingPatched)
+			hasEvent(g, "Tenant", "x-team", corev1.EventTypeNormal, EventAppProjectPatched)
+			hasEvent(g, "Namespace", "app-x", corev1.EventTypeNormal, EventNamespaceAdopted)
+			hasEvent(g, "Tenant", "s-team", corev1.EventTypeWarning, cattagev1beta1.ReasonDelegateNotFound)
+		}).Should(Succeed())
+	})
+
+	It("should inherit repositories and delegates from the ancestors", func() {
+		dept := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dept",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-dept"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Repositories: []string{"https://github.com/cybozu-go/dept"},
+				},
+				Delegates: []cattagev1beta1.DelegateSpec{
+					{
+						Name:  "c-team",
+						Roles: []string{"admin"},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, dept)
+		Expect(err).ToNot(HaveOccurred())
+
+		team := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "dept-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Parent: "dept",
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-dept-team"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Repositories: []string{"https://github.com/cybozu-go/team"},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, team)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			rb := &rbacv1.RoleBinding{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-dept-team", Name: "dept-team-admin"}, rb)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(rb.Subjects).Should(ConsistOf([]rbacv1.Subject{
+				{
+					Kind:     "Group",
+					APIGroup: "rbac.authorization.k8s.io",
+					Name:     "dept-team",
+				},
+				{
+					Kind:     "Group",
+					APIGroup: "rbac.authorization.k8s.io",
+					Name:     "dept",
+				},
+				{
+					Kind:     "Group",
+					APIGroup: "rbac.authorization.k8s.io",
+					Name:     "c-team",
+				},
+			}))
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "dept-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("description", "parent: dept"))
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("sourceRepos", ConsistOf(
+				"https://github.com/cybozu-go/dept",
+				"https://github.com/cybozu-go/team",
+			)))
+		}).Should(Succeed())
+
+		By("changing the parent")
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "dept"}, dept)
+		Expect(err).ToNot(HaveOccurred())
+		dept.Spec.ArgoCD.Repositories = append(dept.Spec.ArgoCD.Repositories, "https://github.com/cybozu-go/dept2")
+		err = k8sClient.Update(ctx, dept)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "dept-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("sourceRepos", ContainElement("https://github.com/cybozu-go/dept2")))
+		}).Should(Succeed())
+	})
+
+	It("should inherit extra parameters when the tenant has none", func() {
+		org := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "org",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-org"},
+				},
+				ExtraParams: &cattagev1beta1.Params{Data: map[string]interface{}{
+					"CPU": "6",
+				}},
+			},
+		}
+		err := k8sClient.Create(ctx, org)
+		Expect(err).ToNot(HaveOccurred())
+
+		team := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "org-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Parent: "org",
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-org-team"},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, team)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			quota := &corev1.ResourceQuota{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-org-team", Name: "org-team-quota"}, quota)
+			g.Expect(err).ToNot(HaveOccurred())
+			cpu := quota.Spec.Hard[corev1.ResourceRequestsCPU]
+			g.Expect(cpu.String()).Should(Equal("6"))
+		}).Should(Succeed())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "org-team"}, team)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(team.Spec.ExtraParams).Should(BeNil())
+			g.Expect(meta.IsStatusConditionTrue(team.Status.Conditions, cattagev1beta1.ConditionReady)).Should(BeTrue())
+		}).Should(Succeed())
+	})
+
+	It("should narrow sync windows to the namespace of the SyncWindow resource", func() {
+		scopeTeam := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "scope-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-scope"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					SyncWindowScope: cattagev1beta1.SyncWindowScopeNamespace,
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, scopeTeam)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func() error {
+			return k8sClient.Get(ctx, client.ObjectKey{Name: "app-scope"}, &corev1.Namespace{})
+		}).Should(Succeed())
+
+		sw := &cattagev1beta1.SyncWindow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "scoped",
+				Namespace: "app-scope",
+			},
+			Spec: cattagev1beta1.SyncWindowSpec{
+				SyncWindows: cattagev1beta1.SyncWindows{
+					{
+						Kind:       "deny",
+						Schedule:   "0 0 * * *",
+						Duration:   "1h",
+						Namespaces: []string{"*"},
+					},
+					{
+						Kind:           "deny",
+						Schedule:       "0 0 * * *",
+						Duration:       "1h",
+						Namespaces:     []string{"other"},
+						UseAndOperator: true,
+					},
+					{
+						Kind:         "allow",
+						Schedule:     "0 0 * * *",
+						Duration:     "1h",
+						Applications: []string{"my-app"},
+					},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, sw)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "scope-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("syncWindows", ConsistOf(
+				MatchAllKeys(Keys{
+					"kind":       Equal("allow"),
+					"schedule":   Equal("0 23 * * *"),
+					"duration":   Equal("1h"),
+					"namespaces": ConsistOf("*-stage"),
+				}),
+				MatchAllKeys(Keys{
+					"kind":       Equal("deny"),
+					"schedule":   Equal("0 0 * * *"),
+					"duration":   Equal("1h"),
+					"namespaces": ConsistOf("app-scope"),
+				}),
+				MatchAllKeys(Keys{
+					"kind":         Equal("allow"),
+					"schedule":     Equal("0 0 * * *"),
+					"duration":     Equal("1h"),
+					"applications": ConsistOf("my-app"),
+					"namespaces":   ConsistOf("app-scope"),
+					"andOperator":  BeTrue(),
+				}),
+			)))
+
+			sw := &cattagev1beta1.SyncWindow{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "scoped"}, sw)
+			g.Expect(err).ToNot(HaveOccurred())
+			cond := meta.FindStatusCondition(sw.Status.Conditions, cattagev1beta1.ConditionNarrowed)
+			g.Expect(cond).ShouldNot(BeNil())
+			g.Expect(cond.Status).Should(Equal(metav1.ConditionTrue))
+			g.Expect(cond.Reason).Should(Equal(cattagev1beta1.ReasonEntriesDropped))
+		}).Should(Succeed())
+	})
+
+	It("should report the status of each SyncWindow resource", func() {
+		invalid := &cattagev1beta1.SyncWindow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "invalid",
+				Namespace: "app-scope",
+			},
+			Spec: cattagev1beta1.SyncWindowSpec{
+				SyncWindows: cattagev1beta1.SyncWindows{
+					{
+						Kind:       "deny",
+						Schedule:   "0 25 * * *",
+						Duration:   "1h",
+						Namespaces: []string{"app-scope"},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, invalid)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			sw := &cattagev1beta1.SyncWindow{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "invalid"}, sw)
+			g.Expect(err).ToNot(HaveOccurred())
+			cond := meta.FindStatusCondition(sw.Status.Conditions, cattagev1beta1.ConditionSynced)
+			g.Expect(cond).ShouldNot(BeNil())
+			g.Expect(cond.Status).Should(Equal(metav1.ConditionFalse))
+			g.Expect(cond.Reason).Should(Equal(cattagev1beta1.ReasonInvalid))
+			g.Expect(sw.Status.ObservedGeneration).Should(Equal(sw.Generation))
+			g.Expect(sw.Status.LastSyncTime).Should(BeNil())
+
+			valid := &cattagev1beta1.SyncWindow{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "scoped"}, valid)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(meta.IsStatusConditionTrue(valid.Status.Conditions, cattagev1beta1.ConditionSynced)).Should(BeTrue())
+			g.Expect(valid.Status.ObservedGeneration).Should(Equal(valid.Generation))
+			g.Expect(valid.Status.AppProject).Should(Equal(&cattagev1beta1.ObjectReference{
+				Namespace: tenantCfg.ArgoCD.Namespace,
+				Name:      "scope-team",
+			}))
+			g.Expect(valid.Status.LastSyncTime).ShouldNot(BeNil())
+
+			proj := argocd.AppProject()
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "scope-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("syncWindows", Not(ContainElement(
+				HaveKeyWithValue("schedule", "0 25 * * *"),
+			))))
+		}).Should(Succeed())
+
+		By("not updating the status when nothing is changed")
+		valid := &cattagev1beta1.SyncWindow{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "scoped"}, valid)
+		Expect(err).ToNot(HaveOccurred())
+		Consistently(func(g Gomega) {
+			sw := &cattagev1beta1.SyncWindow{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "scoped"}, sw)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(sw.ResourceVersion).Should(Equal(valid.ResourceVersion))
+		}, 3*time.Second).Should(Succeed())
+	})
+
+	It("should report whether the sync windows are active", func() {
+		sw := &cattagev1beta1.SyncWindow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "always",
+				Namespace: "app-scope",
+			},
+			Spec: cattagev1beta1.SyncWindowSpec{
+				SyncWindows: cattagev1beta1.SyncWindows{
+					{
+						Kind:       "allow",
+						Schedule:   "* * * * *",
+						Duration:   "1h",
+						Namespaces: []string{"app-scope"},
+					},
+					{
+						Kind:       "deny",
+						Schedule:   "0 0 30 2 *",
+						Duration:   "1h",
+						Namespaces: []string{"app-scope"},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, sw)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			sw := &cattagev1beta1.SyncWindow{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "always"}, sw)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(sw.Status.Windows).Should(HaveLen(2))
+			g.Expect(sw.Status.Windows[0].Kind).Should(Equal("allow"))
+			g.Expect(sw.Status.Windows[0].Active).Should(BeTrue())
+			g.Expect(sw.Status.Windows[0].NextTransitionTime).ShouldNot(BeNil())
+			g.Expect(sw.Status.Windows[0].NextTransitionTime.Time).Should(BeTemporally(">", time.Now()))
+			// February 30th never comes.
+			g.Expect(sw.Status.Windows[1].Kind).Should(Equal("deny"))
+			g.Expect(sw.Status.Windows[1].Active).Should(BeFalse())
+			g.Expect(sw.Status.Windows[1].NextTransitionTime).Should(BeNil())
+
+			g.Expect(testutil.ToFloat64(metrics.SyncWindowActiveVec.WithLabelValues("scope-team", "app-scope", "always", "allow"))).Should(Equal(1.0))
+			g.Expect(testutil.ToFloat64(metrics.SyncWindowActiveVec.WithLabelValues("scope-team", "app-scope", "always", "deny"))).Should(Equal(0.0))
+
+			invalid := &cattagev1beta1.SyncWindow{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-scope", Name: "invalid"}, invalid)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(invalid.Status.Windows).Should(BeEmpty())
+		}).Should(Succeed())
+	})
+
+	It("should append cluster sync windows to the selected tenants", func() {
+		tenant := &cattagev1beta1.Tenant{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: "scope-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Labels = map[string]string{"freeze": "true"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		csw := &cattagev1beta1.ClusterSyncWindow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "holidays",
+			},
+			Spec: cattagev1beta1.ClusterSyncWindowSpec{
+				TenantSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"freeze": "true"},
+				},
+				Tenants: []string{"y-team"},
+				SyncWindows: cattagev1beta1.SyncWindows{
+					{
+						Kind:         "deny",
+						Schedule:     "0 0 24 12 *",
+						Duration:     "72h",
+						Applications: []string{"*"},
+					},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, csw)
+		Expect(err).ToNot(HaveOccurred())
+
+		freeze := MatchAllKeys(Keys{
+			"kind":         Equal("deny"),
+			"schedule":     Equal("0 0 24 12 *"),
+			"duration":     Equal("72h"),
+			"applications": ConsistOf("*"),
+		})
+		Eventually(func(g Gomega) {
+			for _, name := range []string{"scope-team", "y-team"} {
+				proj := argocd.AppProject()
+				err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: name}, proj)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("syncWindows", ContainElement(freeze)), name)
+			}
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "x-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("syncWindows", Not(ContainElement(freeze))))
+		}).Should(Succeed())
+
+		By("removing the sync windows when the resource is deleted")
+		err = k8sClient.Delete(ctx, csw)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func(g Gomega) {
+			for _, name := range []string{"scope-team", "y-team"} {
+				proj := argocd.AppProject()
+				err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: name}, proj)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(proj.UnstructuredContent()["spec"]).Should(HaveKeyWithValue("syncWindows", Not(ContainElement(freeze))), name)
+			}
+		}).Should(Succeed())
+	})
+
+	It("should render resources without applying them", func() {
+		preview := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "preview-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-preview"},
+				},
+			},
+		}
+		objs, err := tr.Render(ctx, preview)
+		Expect(err).ToNot(HaveOccurred())
+		kinds := make([]string, len(objs))
+		for i, obj := range objs {
+			kinds[i] = obj.Desired.GetKind()
+			if obj.Desired.GetKind() != "ConfigMap" {
+				Expect(obj.Managed).Should(BeNil())
+			}
+		}
+		Expect(kinds).Should(Equal([]string{"Namespace", "RoleBinding", "ResourceQuota", "LimitRange", "AppProject", "ConfigMap"}))
+		Expect(objs[5].Desired.Object).Should(HaveKeyWithValue("data", HaveKeyWithValue("application.namespaces", ContainSubstring("app-x"))))
+
+		ns := &corev1.Namespace{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "app-preview"}, ns)
+		Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+
+		By("rendering an existing tenant")
+		tenant := &cattagev1beta1.Tenant{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		objs, err = tr.Render(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		for _, obj := range objs {
+			Expect(obj.Managed).ShouldNot(BeNil(), obj.Desired.GetKind())
+		}
+	})
+
+	It("should reconcile all tenants when the configurations are reloaded", func() {
+		newCfg := *tenantCfg
+		newCfg.Namespace.CommonAnnotations = map[string]string{
+			"hoge": "piyo",
+		}
+		tr.config.Set(&newCfg)
+
+		for _, name := range []string{"app-c", "app-x"} {
+			Eventually(func(g Gomega) {
+				ns := &corev1.Namespace{}
+				err := k8sClient.Get(ctx, client.ObjectKey{Name: name}, ns)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(ns.Annotations).Should(HaveKeyWithValue("hoge", "piyo"))
+			}).Should(Succeed())
+		}
+	})
+
+	It("should assign tenants to application controllers by the sharding policy", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.ApplicationControllers = []string{"second"}
+		newCfg.ArgoCD.Sharding = tenantconfig.ShardingConfig{
+			Policy: tenantconfig.ShardingPolicyLabelSelector,
+			Rules: []tenantconfig.ShardingRule{
+				{
+					TenantSelector: metav1.LabelSelector{MatchLabels: map[string]string{"tier": "critical"}},
+					ControllerName: "second",
+				},
+			},
+		}
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Labels = map[string]string{"tier": "critical"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.ControllerName).Should(Equal("second"))
+			g.Expect(tenant.Status.ConfigMap).Should(Equal(&cattagev1beta1.ObjectReference{Namespace: "argocd", Name: "second-application-controller-cm"}))
+
+			secondCm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "second-application-controller-cm"}, secondCm)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(strings.Split(secondCm.Data["application.namespaces"], ",")).Should(ContainElement("app-x"))
+
+			defaultCm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default-application-controller-cm"}, defaultCm)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(strings.Split(defaultCm.Data["application.namespaces"], ",")).ShouldNot(ContainElement("app-x"))
+		}).Should(Succeed())
+
+		By("assigning the tenant to the default controller again when the policy is removed")
+		tr.config.Set(orig)
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.ControllerName).Should(Equal("default"))
+
+			defaultCm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default-application-controller-cm"}, defaultCm)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(strings.Split(defaultCm.Data["application.namespaces"], ",")).Should(ContainElement("app-x"))
+		}).Should(Succeed())
+	})
+
+	It("should migrate a tenant between application controllers", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.Sharding.MigrationGracePeriod = metav1.Duration{Duration: time.Hour}
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{}
+		err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.ControllerName = "second"
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("removing the namespaces from both controllers until the migration is acknowledged")
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.ControllerName).Should(Equal("second"))
+			g.Expect(tenant.Status.Migration).ShouldNot(BeNil())
+			g.Expect(tenant.Status.Migration.Phase).Should(Equal(cattagev1beta1.ControllerMigrationDraining))
+			g.Expect(tenant.Status.Migration.From).Should(Equal("default"))
+			g.Expect(tenant.Status.Migration.To).Should(Equal("second"))
+			g.Expect(tenant.Status.ConfigMap).Should(BeNil())
+
+			for _, name := range []string{"default-application-controller-cm", "second-application-controller-cm"} {
+				cm := &corev1.ConfigMap{}
+				err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: name}, cm)
+				g.Expect(err).ToNot(HaveOccurred())
+				g.Expect(strings.Split(cm.Data["application.namespaces"], ",")).ShouldNot(ContainElement("app-x"), name)
+			}
+			g.Expect(testutil.ToFloat64(metrics.ControllerMigrationInProgressVec.WithLabelValues("x-team"))).Should(Equal(1.0))
+		}).Should(Succeed())
+
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Annotations = map[string]string{constants.MigrationAckAnnotation: "second"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		By("adding the namespaces to the new controller")
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.Migration).Should(BeNil())
+			g.Expect(tenant.Annotations).ShouldNot(HaveKey(constants.MigrationAckAnnotation))
+			g.Expect(tenant.Status.ConfigMap).Should(Equal(&cattagev1beta1.ObjectReference{Namespace: "argocd", Name: "second-application-controller-cm"}))
+
+			cm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "second-application-controller-cm"}, cm)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(strings.Split(cm.Data["application.namespaces"], ",")).Should(ContainElement("app-x"))
+			g.Expect(testutil.ToFloat64(metrics.ControllerMigrationInProgressVec.WithLabelValues("x-team"))).Should(Equal(0.0))
+			g.Expect(testutil.ToFloat64(metrics.ControllerMigrationsTotal.WithLabelValues("default", "second"))).Should(BeNumerically(">=", 1))
+		}).Should(Succeed())
+
+		By("moving the tenant back at once without the grace period")
+		tr.config.Set(orig)
+		err = k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.ControllerName = ""
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func(g Gomega) {
+			tenant := &cattagev1beta1.Tenant{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "x-team"}, tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(tenant.Status.ControllerName).Should(Equal("default"))
+			g.Expect(tenant.Status.Migration).Should(BeNil())
+
+			cm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default-application-controller-cm"}, cm)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(strings.Split(cm.Data["application.namespaces"], ",")).Should(ContainElement("app-x"))
+		}).Should(Succeed())
+	})
+
+	It("should delete configmaps of application controllers without tenants", func() {
+		stale := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "argocd",
+				Name:      "stale-application-controller-cm",
+				Labels: map[string]string{
+					constants.ManagedByLabel:      "cattage",
+					constants.PartOfLabel:         "argocd",
+					constants.ControllerNameLabel: "stale",
+				},
+			},
+			Data: map[string]string{
+				"application.namespaces": "app-x",
+			},
+		}
+		err := k8sClient.Create(ctx, stale)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			cm := &corev1.ConfigMap{}
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(stale), cm)
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		}).Should(Succeed())
+
+		By("keeping the configmaps of application controllers with tenants")
+		cm := &corev1.ConfigMap{}
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default-application-controller-cm"}, cm)
+		Expect(err).ToNot(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "all-tenant-namespaces-cm"}, cm)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should generate configmaps as configured", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.ConfigMaps = append(slices.Clone(tenantconfig.DefaultConfigMaps), tenantconfig.ConfigMapConfig{
+			Name:      "applicationset-namespaces-cm",
+			Key:       "applicationsetcontroller.namespaces",
+			Separator: " ",
+			Controllers: []string{
+				"second",
+			},
+		})
+		tr.config.Set(&newCfg)
+
+		Eventually(func(g Gomega) {
+			cm := &corev1.ConfigMap{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "applicationset-namespaces-cm"}, cm)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(cm.Labels).Should(HaveKeyWithValue(constants.ManagedByLabel, "cattage"))
+			g.Expect(cm.Labels).ShouldNot(HaveKey(constants.ControllerNameLabel))
+			g.Expect(cm.Data).Should(HaveKey("applicationsetcontroller.namespaces"))
+			g.Expect(strings.Split(cm.Data["applicationsetcontroller.namespaces"], " ")).Should(ContainElement("app-a"))
+		}).Should(Succeed())
+
+		By("deleting the configmap removed from the configuration")
+		tr.config.Set(orig)
+		Eventually(func(g Gomega) {
+			cm := &corev1.ConfigMap{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "applicationset-namespaces-cm"}, cm)
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		}).Should(Succeed())
+	})
+
+	It("should compact namespaces into the namespace patterns", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.CompactNamespaces = true
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "q-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-q"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					NamespacePatterns: []string{"app-q*"},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "q-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			destinations, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "destinations")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(destinations).Should(ConsistOf(
+				MatchAllKeys(Keys{"namespace": Equal("app-q*"), "server": Equal("*")}),
+			))
+
+			cm := &corev1.ConfigMap{}
+			err = k8sClient.Get(ctx, client.ObjectKey{Namespace: "argocd", Name: "default-application-controller-cm"}, cm)
+			g.Expect(err).ToNot(HaveOccurred())
+			namespaces := strings.Split(cm.Data["application.namespaces"], ",")
+			g.Expect(namespaces).Should(ContainElement("app-q*"))
+			g.Expect(namespaces).ShouldNot(ContainElement("app-q"))
+		}).Should(Succeed())
+
+		By("keeping the pattern for a sub-namespace whose owner label is not propagated yet")
+		sub := &corev1.Namespace{}
+		sub.Name = "app-q-sub"
+		sub.Labels = map[string]string{accurate.LabelParent: "app-q"}
+		err = k8sClient.Create(ctx, sub)
+		Expect(err).ToNot(HaveOccurred())
+
+		Consistently(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "q-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			destinations, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "destinations")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(destinations).Should(ConsistOf(
+				MatchAllKeys(Keys{"namespace": Equal("app-q*"), "server": Equal("*")}),
+			))
+		}).Should(Succeed())
+
+		By("listing the namespaces when the pattern matches a namespace of another tenant")
+		ns := &corev1.Namespace{}
+		ns.Name = "app-qa"
+		ns.Labels = map[string]string{constants.OwnerTenant: "no-such-team"}
+		err = k8sClient.Create(ctx, ns)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "q-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			destinations, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "destinations")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(destinations).Should(ConsistOf(
+				MatchAllKeys(Keys{"namespace": Equal("app-q"), "server": Equal("*")}),
+			))
+		}).Should(Succeed())
+
+		tr.config.Set(orig)
+	})
+
+	It("should pass the allowed destination clusters to the appproject template", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.Clusters = []tenantconfig.ClusterConfig{
+			{Name: "remote", Server: "https://remote.example.com"},
+		}
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "v-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-v"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Destinations: []cattagev1beta1.DestinationSpec{
+						{Name: "remote"},
+						{Server: "https://remote.example.com", Namespaces: []string{"v-*"}},
+						{Name: "forbidden", Namespaces: []string{"*"}},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "v-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			destinations, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "destinations")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(destinations).Should(ConsistOf(
+				MatchAllKeys(Keys{"namespace": Equal("app-v"), "server": Equal("*")}),
+				MatchAllKeys(Keys{"namespace": Equal("app-v"), "name": Equal("remote"), "server": Equal("https://remote.example.com")}),
+				MatchAllKeys(Keys{"namespace": Equal("v-*"), "name": Equal("remote"), "server": Equal("https://remote.example.com")}),
+			))
+		}).Should(Succeed())
+
+		tr.config.Set(orig)
+	})
+
+	It("should restrict repositories and generate repository credentials", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.Repositories = tenantconfig.RepositoriesConfig{
+			AllowedPatterns: []string{"https://github.com/cybozu-go/*"},
+			CredentialTemplate: `apiVersion: v1
+kind: Secret
+metadata:
+  labels:
+    argocd.argoproj.io/secret-type: repository
+stringData:
+  url: https://example.com/overridden.git
+  username: {{ .Secret.username }}
+  password: {{ .Secret.password }}
+`,
+		}
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "w-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-w"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Repositories: []string{
+						"https://github.com/cybozu-go/cattage.git",
+						"https://github.com/forbidden/app.git",
+					},
+					RepositoryCredentials: []cattagev1beta1.RepositoryCredentialSpec{
+						{
+							URL:       "https://github.com/cybozu-go/cattage.git",
+							SecretRef: cattagev1beta1.ObjectReference{Namespace: "app-w", Name: "repo-creds"},
+						},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "w-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			repos, _, err := unstructured.NestedStringSlice(proj.UnstructuredContent(), "spec", "sourceRepos")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(repos).Should(ConsistOf("https://github.com/cybozu-go/cattage.git"))
+		}).Should(Succeed())
+
+		By("creating the secret of the credential")
+		source := &corev1.Secret{}
+		source.Namespace = "app-w"
+		source.Name = "repo-creds"
+		source.StringData = map[string]string{"username": "w-team", "password": "secret"}
+		err = k8sClient.Create(ctx, source)
+		Expect(err).ToNot(HaveOccurred())
+
+		secrets := &corev1.SecretList{}
+		Eventually(func(g Gomega) {
+			err := k8sClient.List(ctx, secrets, client.InNamespace(tenantCfg.ArgoCD.Namespace), client.MatchingLabels{constants.OwnerTenant: "w-team"})
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(secrets.Items).Should(HaveLen(1))
+		}).Should(Succeed())
+		secret := secrets.Items[0]
+		Expect(secret.Name).Should(HavePrefix("w-team-repo-"))
+		Expect(secret.Labels).Should(HaveKeyWithValue("argocd.argoproj.io/secret-type", "repository"))
+		Expect(secret.Data).Should(MatchAllKeys(Keys{
+			"url":      BeEquivalentTo("https://github.com/cybozu-go/cattage.git"),
+			"project":  BeEquivalentTo("w-team"),
+			"username": BeEquivalentTo("w-team"),
+			"password": BeEquivalentTo("secret"),
+		}))
+
+		By("updating the secret of the credential")
+		source.StringData = map[string]string{"username": "w-team", "password": "updated"}
+		err = k8sClient.Update(ctx, source)
+		Expect(err).ToNot(HaveOccurred())
+		// The secret is not watched, so the tenant is updated to reconcile it at once.
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Annotations = map[string]string{"touched": "true"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(&secret), &secret)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(secret.Data).Should(HaveKeyWithValue("password", BeEquivalentTo("updated")))
+		}).Should(Succeed())
+
+		By("removing tenant")
+		err = k8sClient.Delete(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(&secret), &corev1.Secret{})
+			g.Expect(apierrors.IsNotFound(err)).Should(BeTrue())
+		}).Should(Succeed())
+
+		tr.config.Set(orig)
+	})
+
+	It("should merge the project roles into the appproject", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.DelegateGroups = []string{"cybozu-go:{{ .Name }}"}
+		tr.config.Set(&newCfg)
+
+		viewer := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "viewer-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Roles: []cattagev1beta1.ProjectRoleSpec{
+						{Name: "member", Groups: []string{"cybozu-go:foreign"}},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, viewer)
+		Expect(err).ToNot(HaveOccurred())
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "roles-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-roles"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Roles: []cattagev1beta1.ProjectRoleSpec{
+						{
+							Name:        "deployer",
+							Description: "deploy applications",
+							Policies: []cattagev1beta1.ProjectPolicySpec{
+								{Resources: []string{"applications"}, Verbs: []string{"get", "sync"}, Applications: []string{"app-roles/*"}},
+								{Resources: []string{"exec"}, Verbs: []string{"create"}, Effect: cattagev1beta1.PolicyEffectDeny},
+							},
+							Groups: []string{"cybozu-go:deployers"},
+						},
+						{
+							Name:   "admin",
+							Groups: []string{"cybozu-go:ignored"},
+						},
+					},
+				},
+				Delegates: []cattagev1beta1.DelegateSpec{
+					{Name: "viewer-team", Roles: []string{"admin"}},
+				},
+			},
+		}
+		err = k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "roles-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			roles, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "roles")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(roles).Should(ConsistOf(
+				MatchAllKeys(Keys{
+					"name":     Equal("admin"),
+					"groups":   ConsistOf("cybozu-go:roles-team", "cybozu-go:viewer-team"),
+					"policies": ConsistOf("p, proj:roles-team:admin, applications, *, roles-team/*, allow"),
+				}),
+				MatchAllKeys(Keys{
+					"name":        Equal("deployer"),
+					"description": Equal("deploy applications"),
+					"groups":      ConsistOf("cybozu-go:deployers"),
+					"policies": ConsistOf(
+						"p, proj:roles-team:deployer, applications, get, roles-team/app-roles/*, allow",
+						"p, proj:roles-team:deployer, applications, sync, roles-team/app-roles/*, allow",
+						"p, proj:roles-team:deployer, exec, create, roles-team/*, deny",
+					),
+				}),
+				MatchAllKeys(Keys{
+					"name":        Equal("delegate-viewer-team"),
+					"description": Equal("Read-only access for the delegated tenant viewer-team"),
+					"groups":      ConsistOf("cybozu-go:viewer-team"),
+					"policies": ConsistOf(
+						"p, proj:roles-team:delegate-viewer-team, applications, get, roles-team/*, allow",
+						"p, proj:roles-team:delegate-viewer-team, applicationsets, get, roles-team/*, allow",
+						"p, proj:roles-team:delegate-viewer-team, logs, get, roles-team/*, allow",
+					),
+				}),
+			))
+		}).Should(Succeed())
+
+		By("overriding the groups of the delegate")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.Delegates[0].Groups = []string{"cybozu-go:auditors"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "roles-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			roles, _, err := unstructured.NestedSlice(proj.UnstructuredContent(), "spec", "roles")
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(roles).Should(ContainElement(MatchKeys(IgnoreExtras, Keys{
+				"name":   Equal("delegate-viewer-team"),
+				"groups": ConsistOf("cybozu-go:auditors"),
+			})))
+		}).Should(Succeed())
+
+		tr.config.Set(orig)
+	})
+
+	It("should merge the resource policy into the appproject", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.ResourcePolicies = []tenantconfig.ResourcePolicyConfig{
+			{
+				Name: "crd",
+				ClusterResourceWhitelist: []metav1.GroupKind{
+					{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"},
+					{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+				},
+				NamespaceResourceBlacklist: []metav1.GroupKind{
+					{Group: "networking.k8s.io", Kind: "NetworkPolicy"},
+				},
+			},
+		}
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "policy-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-policy"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					ResourcePolicy: &cattagev1beta1.ResourcePolicySpec{
+						Profile: "crd",
+						ClusterResourceWhitelist: []metav1.GroupKind{
+							{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"},
+							{Group: "", Kind: "Namespace"},
+						},
+						NamespaceResourceWhitelist: []metav1.GroupKind{
+							{Group: "apps", Kind: "Deployment"},
+						},
+						NamespaceResourceBlacklist: []metav1.GroupKind{
+							{Group: "", Kind: "Secret"},
+						},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			proj := argocd.AppProject()
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: tenantCfg.ArgoCD.Namespace, Name: "policy-team"}, proj)
+			g.Expect(err).ToNot(HaveOccurred())
+			g.Expect(proj.UnstructuredContent()["spec"]).Should(MatchKeys(IgnoreExtras, Keys{
+				"clusterResourceWhitelist": ConsistOf(
+					MatchAllKeys(Keys{"group": Equal("apiextensions.k8s.io"), "kind": Equal("CustomResourceDefinition")}),
+				),
+				"namespaceResourceWhitelist": ConsistOf(
+					MatchAllKeys(Keys{"group": Equal("apps"), "kind": Equal("Deployment")}),
+				),
+				"namespaceResourceBlacklist": ConsistOf(
+					MatchAllKeys(Keys{"group": Equal(""), "kind": Equal("ResourceQuota")}),
+					MatchAllKeys(Keys{"group": Equal(""), "kind": Equal("LimitRange")}),
+					MatchAllKeys(Keys{"group": Equal("networking.k8s.io"), "kind": Equal("NetworkPolicy")}),
+					MatchAllKeys(Keys{"group": Equal(""), "kind": Equal("Secret")}),
+				),
+			}))
+		}).Should(Succeed())
+
+		By("referring to a resource policy that does not exist")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.ArgoCD.ResourcePolicy = &cattagev1beta1.ResourcePolicySpec{Profile: "unknown"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			cond := meta.FindStatusCondition(tenant.Status.Conditions, cattagev1beta1.ConditionAppProjectReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).Should(Equal(cattagev1beta1.ReasonResourcePolicyNotFound))
+		}).Should(Succeed())
+
+		tr.config.Set(orig)
+	})
+
+	It("should apply the defaults of the extra parameters and validate them", func() {
+		orig := tr.config.Get()
+		newCfg := *orig
+		newCfg.ExtraParams.Schema = &apiextensionsv1.JSONSchemaProps{
+			Type: "object",
+			Properties: map[string]apiextensionsv1.JSONSchemaProps{
+				"CPU": {
+					Type:    "string",
+					Pattern: "^[0-9]+$",
+					Default: &apiextensionsv1.JSON{Raw: []byte(`"20"`)},
+				},
+			},
+		}
+		tr.config.Set(&newCfg)
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "params-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-params"},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			quota := &corev1.ResourceQuota{}
+			err := k8sClient.Get(ctx, client.ObjectKey{Namespace: "app-params", Name: "params-team-quota"}, quota)
+			g.Expect(err).ToNot(HaveOccurred())
+			cpu := quota.Spec.Hard[corev1.ResourceRequestsCPU]
+			g.Expect(cpu.String()).Should(Equal("20"))
+		}).Should(Succeed())
+
+		By("specifying an invalid parameter")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(err).ToNot(HaveOccurred())
+		tenant.Spec.ExtraParams = &cattagev1beta1.Params{Data: map[string]interface{}{
+			"CPU": "many",
+		}}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(func(g Gomega) {
+			err := k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+			g.Expect(err).ToNot(HaveOccurred())
+			cond := meta.FindStatusCondition(tenant.Status.Conditions, cattagev1beta1.ConditionNamespacesReady)
+			g.Expect(cond).NotTo(BeNil())
+			g.Expect(cond.Reason).Should(Equal(cattagev1beta1.ReasonInvalidExtraParams))
+			g.Expect(cond.Message).Should(ContainSubstring("extraParams.CPU"))
+		}).Should(Succeed())
+
+		tr.config.Set(orig)
+	})
+
 	Context("Migration to Argo CD 2.5", func() {
 		It("should remove old applications", func() {
 			oldApp, err := fillApplication("app", tenantCfg.ArgoCD.Namespace, "a-team")
@@ -19,13 +19,14 @@ import (
 type applicationValidator struct {
 	client client.Client
 	dec    admission.Decoder
-	config *config.Config
+	config *config.Holder
 }
 
 var _ admission.Handler = &applicationValidator{}
 
 func (v *applicationValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
-	if !v.config.ArgoCD.PreventAppCreationInArgoCDNamespace {
+	cfg := v.config.Get()
+	if !cfg.ArgoCD.PreventAppCreationInArgoCDNamespace {
 		return admission.Allowed("")
 	}
 
@@ -34,18 +35,18 @@ func (v *applicationValidator) Handle(ctx context.Context, req admission.Request
 		return admission.Errored(http.StatusBadRequest, err)
 	}
 
-	if app.GetNamespace() == v.config.ArgoCD.Namespace {
+	if app.GetNamespace() == cfg.ArgoCD.Namespace {
 		if req.Operation != admissionv1.Create {
-			return admission.Allowed("").WithWarnings(fmt.Sprintf("creating Application in %s namespace is forbidden", v.config.ArgoCD.Namespace))
+			return admission.Allowed("").WithWarnings(fmt.Sprintf("creating Application in %s namespace is forbidden", cfg.ArgoCD.Namespace))
 		}
-		return admission.Denied(fmt.Sprintf("cannot create Application in %s namespace", v.config.ArgoCD.Namespace))
+		return admission.Denied(fmt.Sprintf("cannot create Application in %s namespace", cfg.ArgoCD.Namespace))
 	}
 
 	return admission.Allowed("")
 }
 
 // SetupApplicationWebhook registers the webhooks for Application
-func SetupApplicationWebhook(mgr manager.Manager, dec admission.Decoder, config *config.Config) {
+func SetupApplicationWebhook(mgr manager.Manager, dec admission.Decoder, config *config.Holder) {
 	serv := mgr.GetWebhookServer()
 
 	v := &applicationValidator{
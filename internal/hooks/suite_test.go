@@ -17,8 +17,11 @@ import (
 	. "github.com/onsi/gomega"
 	admissionv1beta1 "k8s.io/api/admission/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -36,6 +39,7 @@ import (
 var k8sClient client.Client
 var testEnv *envtest.Environment
 var cancelMgr context.CancelFunc
+var configHolder *config.Holder
 
 func TestAPIs(t *testing.T) {
 	RegisterFailHandler(Fail)
@@ -96,15 +100,54 @@ var _ = BeforeSuite(func() {
 	})
 	Expect(err).NotTo(HaveOccurred())
 
-	config := &config.Config{
+	configHolder = config.NewHolder(&config.Config{
 		Namespace: config.NamespaceConfig{},
 		ArgoCD: config.ArgoCDConfig{
 			Namespace:                           "argocd",
 			PreventAppCreationInArgoCDNamespace: true,
+			ApplicationControllers:              []string{"second"},
+			Clusters: []config.ClusterConfig{
+				{Name: "in-cluster", Server: "https://kubernetes.default.svc"},
+				{Name: "remote"},
+			},
+			Repositories: config.RepositoriesConfig{
+				AllowedPatterns: []string{"https://github.com/cybozu-go/*"},
+				Rules: []config.RepositoryRule{
+					{
+						TenantSelector:  metav1.LabelSelector{MatchLabels: map[string]string{"team": "p"}},
+						AllowedPatterns: []string{"https://github.com/p-team/*"},
+					},
+				},
+			},
+			ResourcePolicies: []config.ResourcePolicyConfig{
+				{Name: "restricted"},
+				{
+					Name:                     "crd",
+					TenantSelector:           &metav1.LabelSelector{MatchLabels: map[string]string{"crd": "true"}},
+					ClusterResourceWhitelist: []metav1.GroupKind{{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}},
+				},
+			},
 		},
-	}
-	SetupTenantWebhook(mgr, admission.NewDecoder(scheme), config)
-	SetupApplicationWebhook(mgr, admission.NewDecoder(scheme), config)
+		Delegation: config.DelegationConfig{
+			AllowedRoles: []string{"admin", "viewer"},
+		},
+		ExtraParams: config.ExtraParamsConfig{
+			Schema: &apiextensionsv1.JSONSchemaProps{
+				Type: "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"cpu": {Type: "integer", Minimum: ptr.To(1.0)},
+					"env": {
+						Type:    "string",
+						Enum:    []apiextensionsv1.JSON{{Raw: []byte(`"dev"`)}, {Raw: []byte(`"prod"`)}},
+						Default: &apiextensionsv1.JSON{Raw: []byte(`"dev"`)},
+					},
+				},
+			},
+		},
+	})
+	SetupTenantWebhook(mgr, admission.NewDecoder(scheme), configHolder)
+	SetupApplicationWebhook(mgr, admission.NewDecoder(scheme), configHolder)
+	SetupSyncWindowWebhook(mgr, admission.NewDecoder(scheme))
 
 	//+kubebuilder:scaffold:webhook
 
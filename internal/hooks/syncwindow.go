@@ -0,0 +1,163 @@
+package hooks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/constants"
+	"github.com/cybozu-go/cattage/internal/syncwindow"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:webhook:path=/validate-cattage-cybozu-io-v1beta1-clustersyncwindow,mutating=false,failurePolicy=fail,sideEffects=None,groups=cattage.cybozu.io,resources=clustersyncwindows,verbs=create;update,versions=v1beta1,name=vclustersyncwindow.kb.io,admissionReviewVersions={v1}
+//+kubebuilder:webhook:path=/validate-cattage-cybozu-io-v1beta1-syncwindow,mutating=false,failurePolicy=fail,sideEffects=None,groups=cattage.cybozu.io,resources=syncwindows,verbs=create;update,versions=v1beta1,name=vsyncwindow.kb.io,admissionReviewVersions={v1}
+
+type syncWindowValidator struct {
+	client client.Client
+	dec    admission.Decoder
+}
+
+var _ admission.Handler = &syncWindowValidator{}
+
+func (v *syncWindowValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	sw := &cattagev1beta1.SyncWindow{}
+	if err := v.dec.Decode(req, sw); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	allErrs := syncwindow.Validate(sw)
+	if len(allErrs) != 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	ns := &corev1.Namespace{}
+	if err := v.client.Get(ctx, client.ObjectKey{Name: sw.Namespace}, ns); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	owner := ns.Labels[constants.OwnerTenant]
+	if owner == "" {
+		return admission.Allowed("").WithWarnings(fmt.Sprintf("namespace %s does not belong to any tenant; this SyncWindow is ignored", sw.Namespace))
+	}
+
+	nss := &corev1.NamespaceList{}
+	if err := v.client.List(ctx, nss); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	p := field.NewPath("spec", "syncWindows")
+	var warnings admission.Warnings
+	for i, w := range sw.Spec.SyncWindows {
+		for j, pattern := range w.Namespaces {
+			warns, errs := validateNamespacePattern(p.Index(i).Child("namespaces").Index(j), pattern, owner, nss.Items)
+			warnings = append(warnings, warns...)
+			allErrs = append(allErrs, errs...)
+		}
+		for j, app := range w.Applications {
+			// Applications in other namespaces than the Argo CD namespace can be specified as `<namespace>/<name>`.
+			nsPattern, _, found := strings.Cut(app, "/")
+			if !found {
+				continue
+			}
+			warns, errs := validateNamespacePattern(p.Index(i).Child("applications").Index(j), nsPattern, owner, nss.Items)
+			warnings = append(warnings, warns...)
+			allErrs = append(allErrs, errs...)
+		}
+	}
+	if len(allErrs) != 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// validateNamespacePattern checks that the pattern matches only namespaces owned by the tenant.
+func validateNamespacePattern(p *field.Path, pattern, tenant string, nss []corev1.Namespace) (admission.Warnings, field.ErrorList) {
+	var allErrs field.ErrorList
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, append(allErrs, field.Invalid(p, pattern, err.Error()))
+	}
+	matched := false
+	for _, ns := range nss {
+		if ok, _ := path.Match(pattern, ns.Name); !ok {
+			continue
+		}
+		if owner := ns.Labels[constants.OwnerTenant]; owner != tenant {
+			allErrs = append(allErrs, field.Forbidden(p, fmt.Sprintf("%q matches namespace %s that does not belong to tenant %s", pattern, ns.Name, tenant)))
+			continue
+		}
+		matched = true
+	}
+	if len(allErrs) != 0 {
+		return nil, allErrs
+	}
+	if !matched {
+		return admission.Warnings{fmt.Sprintf("%s: %q does not match any namespace of tenant %s", p, pattern, tenant)}, nil
+	}
+	return nil, nil
+}
+
+type clusterSyncWindowValidator struct {
+	client client.Client
+	dec    admission.Decoder
+}
+
+var _ admission.Handler = &clusterSyncWindowValidator{}
+
+func (v *clusterSyncWindowValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	csw := &cattagev1beta1.ClusterSyncWindow{}
+	if err := v.dec.Decode(req, csw); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	if allErrs := syncwindow.ValidateCluster(csw); len(allErrs) != 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+
+	var warnings admission.Warnings
+	for _, name := range csw.Spec.Tenants {
+		err := v.client.Get(ctx, client.ObjectKey{Name: name}, &cattagev1beta1.Tenant{})
+		if apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf("tenant %s does not exist", name))
+			continue
+		}
+		if err != nil {
+			return admission.Errored(http.StatusInternalServerError, err)
+		}
+	}
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// SetupSyncWindowWebhook registers the webhooks for SyncWindow and ClusterSyncWindow
+func SetupSyncWindowWebhook(mgr manager.Manager, dec admission.Decoder) {
+	serv := mgr.GetWebhookServer()
+
+	v := &syncWindowValidator{
+		client: mgr.GetClient(),
+		dec:    dec,
+	}
+	serv.Register("/validate-cattage-cybozu-io-v1beta1-syncwindow", &webhook.Admission{Handler: v})
+
+	cv := &clusterSyncWindowValidator{
+		client: mgr.GetClient(),
+		dec:    dec,
+	}
+	serv.Register("/validate-cattage-cybozu-io-v1beta1-clustersyncwindow", &webhook.Admission{Handler: cv})
+}
@@ -0,0 +1,198 @@
+package hooks
+
+import (
+	"context"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newSyncWindow(name, namespace string, setting cattagev1beta1.SyncWindowSetting) *cattagev1beta1.SyncWindow {
+	return &cattagev1beta1.SyncWindow{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: cattagev1beta1.SyncWindowSpec{
+			SyncWindows: cattagev1beta1.SyncWindows{&setting},
+		},
+	}
+}
+
+var _ = Describe("SyncWindow webhook", func() {
+	ctx := context.Background()
+
+	It("should allow creating a valid sync window", func() {
+		sw := newSyncWindow("valid", "sub-1", cattagev1beta1.SyncWindowSetting{
+			Kind:         "deny",
+			Schedule:     "0 22 * * *",
+			Duration:     "1h30m",
+			TimeZone:     "Asia/Tokyo",
+			Namespaces:   []string{"app-a-team", "sub-1"},
+			Applications: []string{"sub-1/*", "my-app"},
+		})
+		err := k8sClient.Create(ctx, sw)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny creating an invalid sync window", func() {
+		testcases := []struct {
+			name    string
+			setting cattagev1beta1.SyncWindowSetting
+			message string
+		}{
+			{
+				name: "unknown kind",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "block", Schedule: "0 22 * * *", Duration: "1h", Namespaces: []string{"sub-1"},
+				},
+				message: "spec.syncWindows[0].kind",
+			},
+			{
+				name: "invalid schedule",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 25 * * *", Duration: "1h", Namespaces: []string{"sub-1"},
+				},
+				message: "spec.syncWindows[0].schedule",
+			},
+			{
+				name: "invalid duration",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 22 * * *", Duration: "1 hour", Namespaces: []string{"sub-1"},
+				},
+				message: "spec.syncWindows[0].duration",
+			},
+			{
+				name: "unknown time zone",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 22 * * *", Duration: "1h", TimeZone: "Mars/Olympus", Namespaces: []string{"sub-1"},
+				},
+				message: "spec.syncWindows[0].timeZone",
+			},
+			{
+				name: "no targets",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 22 * * *", Duration: "1h",
+				},
+				message: "one of applications, namespaces or clusters is required",
+			},
+			{
+				name: "other tenant's namespace",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 22 * * *", Duration: "1h", Namespaces: []string{"app-y-team"},
+				},
+				message: "matches namespace app-y-team that does not belong to tenant a-team",
+			},
+			{
+				name: "pattern matching other tenant's namespace",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 22 * * *", Duration: "1h", Namespaces: []string{"sub-*"},
+				},
+				message: "matches namespace sub-2 that does not belong to tenant a-team",
+			},
+			{
+				name: "application in other tenant's namespace",
+				setting: cattagev1beta1.SyncWindowSetting{
+					Kind: "allow", Schedule: "0 22 * * *", Duration: "1h", Applications: []string{"sub-2/my-app"},
+				},
+				message: "matches namespace sub-2 that does not belong to tenant a-team",
+			},
+		}
+		for _, tc := range testcases {
+			By(tc.name)
+			sw := newSyncWindow("invalid", "sub-1", tc.setting)
+			err := k8sClient.Create(ctx, sw)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+})
+
+var _ = Describe("ClusterSyncWindow webhook", func() {
+	ctx := context.Background()
+
+	freeze := cattagev1beta1.SyncWindowSetting{
+		Kind:         "deny",
+		Schedule:     "0 0 24 12 *",
+		Duration:     "72h",
+		Applications: []string{"*"},
+	}
+
+	It("should allow creating a valid cluster sync window", func() {
+		var warnings []string
+		cfg := rest.CopyConfig(testEnv.Config)
+		cfg.WarningHandler = warningRecorder(func(msg string) {
+			warnings = append(warnings, msg)
+		})
+		c, err := client.New(cfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+
+		csw := &cattagev1beta1.ClusterSyncWindow{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "holidays",
+			},
+			Spec: cattagev1beta1.ClusterSyncWindowSpec{
+				TenantSelector: &metav1.LabelSelector{},
+				Tenants:        []string{"no-such-team"},
+				SyncWindows:    cattagev1beta1.SyncWindows{&freeze},
+			},
+		}
+		err = c.Create(ctx, csw)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).Should(ContainElement("tenant no-such-team does not exist"))
+	})
+
+	It("should deny creating an invalid cluster sync window", func() {
+		testcases := []struct {
+			name    string
+			spec    cattagev1beta1.ClusterSyncWindowSpec
+			message string
+		}{
+			{
+				name: "no tenants",
+				spec: cattagev1beta1.ClusterSyncWindowSpec{
+					SyncWindows: cattagev1beta1.SyncWindows{&freeze},
+				},
+				message: "one of tenantSelector or tenants is required",
+			},
+			{
+				name: "invalid selector",
+				spec: cattagev1beta1.ClusterSyncWindowSpec{
+					TenantSelector: &metav1.LabelSelector{
+						MatchExpressions: []metav1.LabelSelectorRequirement{
+							{Key: "tier", Operator: metav1.LabelSelectorOpIn},
+						},
+					},
+					SyncWindows: cattagev1beta1.SyncWindows{&freeze},
+				},
+				message: "spec.tenantSelector.matchExpressions[0].values",
+			},
+			{
+				name: "invalid sync window",
+				spec: cattagev1beta1.ClusterSyncWindowSpec{
+					Tenants: []string{"a-team"},
+					SyncWindows: cattagev1beta1.SyncWindows{
+						{Kind: "allow", Schedule: "0 22 * * *", Duration: "1 hour", Applications: []string{"*"}},
+					},
+				},
+				message: "spec.syncWindows[0].duration",
+			},
+		}
+		for _, tc := range testcases {
+			By(tc.name)
+			csw := &cattagev1beta1.ClusterSyncWindow{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "invalid",
+				},
+				Spec: tc.spec,
+			}
+			err := k8sClient.Create(ctx, csw)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+})
@@ -1,9 +1,16 @@
 package hooks
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/http"
+	"path"
+	"slices"
+	"strings"
 
 	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
 	"github.com/cybozu-go/cattage/internal/accurate"
@@ -11,7 +18,10 @@ import (
 	"github.com/cybozu-go/cattage/internal/constants"
 	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
@@ -50,7 +60,7 @@ func (m *tenantMutator) Handle(ctx context.Context, req admission.Request) admis
 type tenantValidator struct {
 	client client.Client
 	dec    admission.Decoder
-	config *config.Config
+	config *config.Holder
 }
 
 var _ admission.Handler = &tenantValidator{}
@@ -64,10 +74,24 @@ func (v *tenantValidator) Handle(ctx context.Context, req admission.Request) adm
 	if err := v.dec.Decode(req, tenant); err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	// The finalizer must be removable even if the tenant is no longer valid for the current configuration.
+	if tenant.DeletionTimestamp != nil {
+		return admission.Allowed("")
+	}
+	// The checks depending on the configuration apply only to the fields changed by the update,
+	// so that tenants created under an older configuration can still be updated.
+	var old *cattagev1beta1.Tenant
+	if req.Operation == admissionv1.Update {
+		old = &cattagev1beta1.Tenant{}
+		if err := v.dec.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+	}
 	tenantList := &cattagev1beta1.TenantList{}
 	if err := v.client.List(ctx, tenantList); err != nil {
 		return admission.Errored(http.StatusBadRequest, err)
 	}
+	cfg := v.config.Get()
 
 	for _, ns := range tenant.Spec.RootNamespaces {
 		for _, t := range tenantList.Items {
@@ -103,11 +127,367 @@ func (v *tenantValidator) Handle(ctx context.Context, req admission.Request) adm
 		}
 	}
 
-	return admission.Allowed("")
+	warnings, err := v.validateDelegates(cfg, tenant, old, tenantList.Items)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	if (old == nil || old.Spec.ControllerName != tenant.Spec.ControllerName) && !cfg.IsKnownController(tenant.Spec.ControllerName) {
+		return admission.Denied(fmt.Sprintf("unknown application controller: %s", tenant.Spec.ControllerName))
+	}
+	if err := v.validateDestinations(cfg, tenant, old); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if err := validateProjectRoles(tenant); err != nil {
+		return admission.Denied(err.Error())
+	}
+	if old == nil || old.Spec.Parent != tenant.Spec.Parent || !equality.Semantic.DeepEqual(old.Spec.ExtraParams, tenant.Spec.ExtraParams) {
+		if err := v.validateExtraParams(cfg, tenant, tenantList.Items); err != nil {
+			return admission.Denied(err.Error())
+		}
+	}
+	if old == nil || !equality.Semantic.DeepEqual(old.Spec.ArgoCD.ResourcePolicy, tenant.Spec.ArgoCD.ResourcePolicy) {
+		policyWarnings, err := v.validateResourcePolicy(cfg, tenant)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+		warnings = append(warnings, policyWarnings...)
+	}
+	if old == nil || old.Spec.ResourceQuotaTemplate != tenant.Spec.ResourceQuotaTemplate || old.Spec.LimitRangeTemplate != tenant.Spec.LimitRangeTemplate {
+		if allErrs := cfg.ValidateTenantTemplates(&tenant.Spec); len(allErrs) != 0 {
+			return admission.Denied(allErrs.ToAggregate().Error())
+		}
+	}
+	repoWarnings, err := v.validateRepositories(ctx, cfg, tenant, old)
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	warnings = append(warnings, repoWarnings...)
+	if old == nil || old.Spec.Parent != tenant.Spec.Parent {
+		parentWarnings, err := validateParent(tenant, tenantList.Items)
+		if err != nil {
+			return admission.Denied(err.Error())
+		}
+		warnings = append(warnings, parentWarnings...)
+	}
+	patternWarnings, allErrs, err := v.validateNamespacePatterns(ctx, tenant, old, tenantList.Items)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	if len(allErrs) != 0 {
+		return admission.Denied(allErrs.ToAggregate().Error())
+	}
+	warnings = append(warnings, patternWarnings...)
+
+	return admission.Allowed("").WithWarnings(warnings...)
+}
+
+// validateDelegates checks the delegates of the tenant.
+// The roles already delegated before the update are not checked against the configuration.
+func (v *tenantValidator) validateDelegates(cfg *config.Config, tenant, old *cattagev1beta1.Tenant, tenants []cattagev1beta1.Tenant) (admission.Warnings, error) {
+	oldRoles := make(map[string][]string)
+	if old != nil {
+		for _, d := range old.Spec.Delegates {
+			oldRoles[d.Name] = d.Roles
+		}
+	}
+	var warnings admission.Warnings
+	seen := make(map[string]bool, len(tenant.Spec.Delegates))
+	for _, d := range tenant.Spec.Delegates {
+		if d.Name == tenant.Name {
+			return nil, errors.New("tenant cannot delegate to itself")
+		}
+		if seen[d.Name] {
+			return nil, fmt.Errorf("duplicate delegate: %s", d.Name)
+		}
+		seen[d.Name] = true
+
+		if len(d.Roles) == 0 {
+			return nil, fmt.Errorf("roles for delegate %s should not be empty", d.Name)
+		}
+		for _, role := range d.Roles {
+			if !slices.Contains(oldRoles[d.Name], role) && !cfg.IsAllowedRole(role) {
+				return nil, fmt.Errorf("role %s for delegate %s is not allowed", role, d.Name)
+			}
+		}
+
+		if !slices.ContainsFunc(tenants, func(t cattagev1beta1.Tenant) bool { return t.Name == d.Name }) {
+			warnings = append(warnings, fmt.Sprintf("delegated tenant %s does not exist", d.Name))
+		}
+	}
+	return warnings, nil
+}
+
+// validateDestinations checks that the destination clusters are allowed in the configuration.
+// The clusters that the tenant already had before the update are not checked against the configuration.
+func (v *tenantValidator) validateDestinations(cfg *config.Config, tenant, old *cattagev1beta1.Tenant) error {
+	for _, d := range tenant.Spec.ArgoCD.Destinations {
+		if d.Name == "" && d.Server == "" {
+			return errors.New("name or server is required for destinations")
+		}
+		existing := old != nil && slices.ContainsFunc(old.Spec.ArgoCD.Destinations, func(o cattagev1beta1.DestinationSpec) bool {
+			return o.Name == d.Name && o.Server == d.Server
+		})
+		if _, ok := cfg.AllowedCluster(d.Name, d.Server); !existing && !ok {
+			return fmt.Errorf("destination cluster is not allowed: %s", cmp.Or(d.Name, d.Server))
+		}
+		for _, pattern := range d.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid namespace pattern %q for destination %s: %w", pattern, cmp.Or(d.Name, d.Server), err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateRepositories checks that the repositories and the repository credentials are allowed in the configuration,
+// and that the Secrets of the credentials are in the namespaces of the tenant.
+// The repositories that the tenant already had before the update are not checked against the configuration.
+func (v *tenantValidator) validateRepositories(ctx context.Context, cfg *config.Config, tenant, old *cattagev1beta1.Tenant) (admission.Warnings, error) {
+	var oldRepos, oldCreds []string
+	if old != nil {
+		oldRepos = old.Spec.ArgoCD.Repositories
+		for _, cred := range old.Spec.ArgoCD.RepositoryCredentials {
+			oldCreds = append(oldCreds, cred.URL)
+		}
+	}
+	for _, repo := range tenant.Spec.ArgoCD.Repositories {
+		if !slices.Contains(oldRepos, repo) && !cfg.IsAllowedRepository(repo, tenant.Labels) {
+			return nil, fmt.Errorf("repository is not allowed: %s", repo)
+		}
+	}
+	if len(tenant.Spec.ArgoCD.RepositoryCredentials) == 0 {
+		return nil, nil
+	}
+
+	var warnings admission.Warnings
+	if cfg.ArgoCD.Repositories.CredentialTemplate == "" {
+		warnings = append(warnings, "repository credentials are not generated because the template is not configured")
+	}
+	seen := make(map[string]bool, len(tenant.Spec.ArgoCD.RepositoryCredentials))
+	for _, cred := range tenant.Spec.ArgoCD.RepositoryCredentials {
+		if seen[cred.URL] {
+			return nil, fmt.Errorf("duplicate repository credential: %s", cred.URL)
+		}
+		seen[cred.URL] = true
+		if !slices.Contains(oldCreds, cred.URL) && !cfg.IsAllowedRepository(cred.URL, tenant.Labels) {
+			return nil, fmt.Errorf("repository of the credential is not allowed: %s", cred.URL)
+		}
+
+		// Root namespaces of the tenant will be adopted by it even if they do not belong to any tenant yet.
+		isRoot := slices.ContainsFunc(tenant.Spec.RootNamespaces, func(root cattagev1beta1.RootNamespaceSpec) bool {
+			return root.Name == cred.SecretRef.Namespace
+		})
+		ns := &corev1.Namespace{}
+		err := v.client.Get(ctx, client.ObjectKey{Name: cred.SecretRef.Namespace}, ns)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		owner := ns.Labels[constants.OwnerTenant]
+		if owner != tenant.Name && !(isRoot && owner == "") {
+			return nil, fmt.Errorf("secret of the repository credential %s must be in a namespace of the tenant", cred.URL)
+		}
+
+		secret := &corev1.Secret{}
+		err = v.client.Get(ctx, client.ObjectKey{Namespace: cred.SecretRef.Namespace, Name: cred.SecretRef.Name}, secret)
+		if apierrors.IsNotFound(err) {
+			warnings = append(warnings, fmt.Sprintf("secret %s/%s of the repository credential does not exist", cred.SecretRef.Namespace, cred.SecretRef.Name))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return warnings, nil
+}
+
+// validateProjectRoles checks that the names of the project roles are unique and not reserved.
+func validateProjectRoles(tenant *cattagev1beta1.Tenant) error {
+	seen := make(map[string]bool, len(tenant.Spec.ArgoCD.Roles))
+	for _, role := range tenant.Spec.ArgoCD.Roles {
+		if strings.HasPrefix(role.Name, constants.DelegateRolePrefix) {
+			return fmt.Errorf("role name %s is reserved for delegated tenants", role.Name)
+		}
+		if seen[role.Name] {
+			return fmt.Errorf("duplicate role: %s", role.Name)
+		}
+		seen[role.Name] = true
+	}
+	return nil
+}
+
+// validateExtraParams checks that the extra parameters of the tenant conform to the schema in the configuration.
+// The parameters inherited from the ancestors and the defaults in the schema are taken into account as the controller does.
+func (v *tenantValidator) validateExtraParams(cfg *config.Config, tenant *cattagev1beta1.Tenant, tenants []cattagev1beta1.Tenant) error {
+	if cfg.ExtraParams.Schema == nil {
+		return nil
+	}
+	parents := make(map[string]*cattagev1beta1.Tenant, len(tenants))
+	for i := range tenants {
+		parents[tenants[i].Name] = &tenants[i]
+	}
+
+	params := maps.Clone(tenant.Spec.ExtraParams.ToMap())
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	visited := map[string]bool{tenant.Name: true}
+	for name := tenant.Spec.Parent; name != "" && !visited[name]; {
+		visited[name] = true
+		parent, ok := parents[name]
+		if !ok {
+			break
+		}
+		for k, val := range parent.Spec.ExtraParams.ToMap() {
+			if _, ok := params[k]; !ok {
+				params[k] = val
+			}
+		}
+		name = parent.Spec.Parent
+	}
+
+	if err := cfg.ValidateExtraParams(cfg.DefaultExtraParams(params)); err != nil {
+		return fmt.Errorf("invalid extra parameters: %w", err)
+	}
+	return nil
+}
+
+// validateResourcePolicy checks that the resource policy of the tenant is allowed in the configuration.
+// The whitelists of the tenant that are not allowed by the policy in the configuration are warned
+// because they may be allowed by the template for AppProject.
+func (v *tenantValidator) validateResourcePolicy(cfg *config.Config, tenant *cattagev1beta1.Tenant) (admission.Warnings, error) {
+	spec := tenant.Spec.ArgoCD.ResourcePolicy
+	if spec == nil {
+		return nil, nil
+	}
+	profile, err := cfg.ResourcePolicy(spec.Profile, tenant.Labels)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		profile = &config.ResourcePolicyConfig{}
+	}
+
+	// An empty namespaceResourceWhitelist allows any resource, while an empty clusterResourceWhitelist allows none.
+	lists := []struct {
+		name      string
+		entries   []metav1.GroupKind
+		whitelist []metav1.GroupKind
+		restrict  bool
+	}{
+		{"clusterResourceWhitelist", spec.ClusterResourceWhitelist, profile.ClusterResourceWhitelist, true},
+		{"clusterResourceBlacklist", spec.ClusterResourceBlacklist, nil, false},
+		{"namespaceResourceWhitelist", spec.NamespaceResourceWhitelist, profile.NamespaceResourceWhitelist, len(profile.NamespaceResourceWhitelist) != 0},
+		{"namespaceResourceBlacklist", spec.NamespaceResourceBlacklist, nil, false},
+	}
+	var warnings admission.Warnings
+	for _, l := range lists {
+		for _, gk := range l.entries {
+			if gk.Kind == "" {
+				return nil, fmt.Errorf("kind is required in %s", l.name)
+			}
+			if !l.restrict {
+				continue
+			}
+			allowed := slices.ContainsFunc(l.whitelist, func(p metav1.GroupKind) bool {
+				groupOK, _ := path.Match(p.Group, gk.Group)
+				kindOK, _ := path.Match(p.Kind, gk.Kind)
+				return groupOK && kindOK
+			})
+			if !allowed {
+				warnings = append(warnings, fmt.Sprintf("%s/%s in %s is ignored unless the template for AppProject allows it", gk.Group, gk.Kind, l.name))
+			}
+		}
+	}
+	return warnings, nil
+}
+
+// validateNamespacePatterns checks that the namespace patterns match neither namespaces of other tenants
+// nor root namespaces of other tenants that do not exist yet.
+// The patterns that the tenant already had before the update are not checked.
+func (v *tenantValidator) validateNamespacePatterns(ctx context.Context, tenant, old *cattagev1beta1.Tenant, tenants []cattagev1beta1.Tenant) (admission.Warnings, field.ErrorList, error) {
+	isNew := func(pattern string) bool {
+		return old == nil || !slices.Contains(old.Spec.ArgoCD.NamespacePatterns, pattern)
+	}
+	if !slices.ContainsFunc(tenant.Spec.ArgoCD.NamespacePatterns, isNew) {
+		return nil, nil, nil
+	}
+	nss := &corev1.NamespaceList{}
+	if err := v.client.List(ctx, nss); err != nil {
+		return nil, nil, err
+	}
+	// Root namespaces of the tenant will be adopted by it even if they do not belong to any tenant yet.
+	for i, ns := range nss.Items {
+		if ns.Labels[constants.OwnerTenant] == "" && slices.ContainsFunc(tenant.Spec.RootNamespaces, func(root cattagev1beta1.RootNamespaceSpec) bool {
+			return root.Name == ns.Name
+		}) {
+			nss.Items[i].Labels = map[string]string{constants.OwnerTenant: tenant.Name}
+		}
+	}
+
+	p := field.NewPath("spec", "argocd", "namespacePatterns")
+	var warnings admission.Warnings
+	var allErrs field.ErrorList
+	for i, pattern := range tenant.Spec.ArgoCD.NamespacePatterns {
+		if !isNew(pattern) {
+			continue
+		}
+		warns, errs := validateNamespacePattern(p.Index(i), pattern, tenant.Name, nss.Items)
+		if len(errs) != 0 {
+			allErrs = append(allErrs, errs...)
+			continue
+		}
+		for _, t := range tenants {
+			if t.Name == tenant.Name {
+				continue
+			}
+			for _, ns := range t.Spec.RootNamespaces {
+				if ok, _ := path.Match(pattern, ns.Name); ok {
+					errs = append(errs, field.Forbidden(p.Index(i), fmt.Sprintf("%q matches root namespace %s of tenant %s", pattern, ns.Name, t.Name)))
+				}
+			}
+		}
+		if len(errs) != 0 {
+			allErrs = append(allErrs, errs...)
+			continue
+		}
+		// Root namespaces that do not exist yet will match the pattern.
+		if len(warns) != 0 && slices.ContainsFunc(tenant.Spec.RootNamespaces, func(ns cattagev1beta1.RootNamespaceSpec) bool {
+			ok, _ := path.Match(pattern, ns.Name)
+			return ok
+		}) {
+			warns = nil
+		}
+		warnings = append(warnings, warns...)
+	}
+	return warnings, allErrs, nil
+}
+
+func validateParent(tenant *cattagev1beta1.Tenant, tenants []cattagev1beta1.Tenant) (admission.Warnings, error) {
+	if tenant.Spec.Parent == "" {
+		return nil, nil
+	}
+	if tenant.Spec.Parent == tenant.Name {
+		return nil, errors.New("tenant cannot be its own parent")
+	}
+	parents := make(map[string]string, len(tenants))
+	for _, t := range tenants {
+		parents[t.Name] = t.Spec.Parent
+	}
+	if _, ok := parents[tenant.Spec.Parent]; !ok {
+		return admission.Warnings{fmt.Sprintf("parent tenant %s does not exist", tenant.Spec.Parent)}, nil
+	}
+	visited := map[string]bool{tenant.Name: true}
+	for name := tenant.Spec.Parent; name != ""; name = parents[name] {
+		if visited[name] {
+			return nil, errors.New("tenant hierarchy must not have a cycle")
+		}
+		visited[name] = true
+	}
+	return nil, nil
 }
 
 // SetupTenantWebhook registers the webhooks for Tenant
-func SetupTenantWebhook(mgr manager.Manager, dec admission.Decoder, config *config.Config) {
+func SetupTenantWebhook(mgr manager.Manager, dec admission.Decoder, config *config.Holder) {
 	serv := mgr.GetWebhookServer()
 
 	m := &tenantMutator{
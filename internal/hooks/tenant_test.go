@@ -4,10 +4,14 @@ import (
 	"context"
 
 	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/cybozu-go/cattage/internal/config"
 	"github.com/cybozu-go/cattage/internal/constants"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
@@ -90,4 +94,629 @@ var _ = Describe("Tenant webhook", func() {
 		Expect(err.Error()).Should(ContainSubstring("sub namespace is not allowed"))
 	})
 
+	It("should allow creating a tenant with delegates and a known controller", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "h-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Delegates: []cattagev1beta1.DelegateSpec{
+					{
+						Name:  "a-team",
+						Roles: []string{"admin", "viewer"},
+					},
+				},
+				ControllerName: "second",
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny creating a tenant with invalid delegates", func() {
+		testcases := []struct {
+			name      string
+			delegates []cattagev1beta1.DelegateSpec
+			message   string
+		}{
+			{
+				name: "self-delegation",
+				delegates: []cattagev1beta1.DelegateSpec{
+					{Name: "i-team", Roles: []string{"admin"}},
+				},
+				message: "tenant cannot delegate to itself",
+			},
+			{
+				name: "duplicate delegates",
+				delegates: []cattagev1beta1.DelegateSpec{
+					{Name: "a-team", Roles: []string{"admin"}},
+					{Name: "a-team", Roles: []string{"viewer"}},
+				},
+				message: "duplicate delegate: a-team",
+			},
+			{
+				name: "empty roles",
+				delegates: []cattagev1beta1.DelegateSpec{
+					{Name: "a-team"},
+				},
+				message: "roles for delegate a-team should not be empty",
+			},
+			{
+				name: "role not allowed",
+				delegates: []cattagev1beta1.DelegateSpec{
+					{Name: "a-team", Roles: []string{"owner"}},
+				},
+				message: "role owner for delegate a-team is not allowed",
+			},
+		}
+		for _, tc := range testcases {
+			By(tc.name)
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "i-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					Delegates: tc.delegates,
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should warn creating a tenant delegating to a tenant that does not exist", func() {
+		var warnings []string
+		cfg := rest.CopyConfig(testEnv.Config)
+		cfg.WarningHandler = warningRecorder(func(msg string) {
+			warnings = append(warnings, msg)
+		})
+		c, err := client.New(cfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "j-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Delegates: []cattagev1beta1.DelegateSpec{
+					{Name: "no-such-team", Roles: []string{"admin"}},
+				},
+			},
+		}
+		err = c.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).Should(ContainElement("delegated tenant no-such-team does not exist"))
+	})
+
+	It("should deny creating a tenant with an unknown controller", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "k-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				ControllerName: "third",
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("unknown application controller: third"))
+	})
+
+	It("should deny creating a tenant whose parent is itself", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "f-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Parent: "f-team",
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("tenant cannot be its own parent"))
+	})
+
+	It("should deny updating a tenant to make a cycle in the hierarchy", func() {
+		parent := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "g-dept",
+			},
+		}
+		err := k8sClient.Create(ctx, parent)
+		Expect(err).NotTo(HaveOccurred())
+
+		child := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "g-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Parent: "g-dept",
+			},
+		}
+		err = k8sClient.Create(ctx, child)
+		Expect(err).NotTo(HaveOccurred())
+
+		parent.Spec.Parent = "g-team"
+		err = k8sClient.Update(ctx, parent)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("tenant hierarchy must not have a cycle"))
+	})
+
+	It("should allow creating a tenant with namespace patterns matching only its namespaces", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "l-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-l-team"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					NamespacePatterns: []string{"app-l-*"},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny creating a tenant with namespace patterns matching other tenants' namespaces", func() {
+		testcases := []struct {
+			pattern string
+			message string
+		}{
+			{pattern: "app-*", message: "matches namespace app-a-team that does not belong to tenant m-team"},
+			{pattern: "app-l-*", message: "matches root namespace app-l-team of tenant l-team"},
+			{pattern: "[", message: "syntax error in pattern"},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "m-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					ArgoCD: cattagev1beta1.ArgoCDSpec{
+						NamespacePatterns: []string{tc.pattern},
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+	It("should allow creating a tenant with allowed destinations", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "n-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Destinations: []cattagev1beta1.DestinationSpec{
+						{Server: "https://kubernetes.default.svc"},
+						{Name: "remote", Namespaces: []string{"n-team-*"}},
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny creating a tenant with invalid destinations", func() {
+		testcases := []struct {
+			destination cattagev1beta1.DestinationSpec
+			message     string
+		}{
+			{destination: cattagev1beta1.DestinationSpec{Namespaces: []string{"*"}}, message: "name or server is required for destinations"},
+			{destination: cattagev1beta1.DestinationSpec{Name: "unknown"}, message: "destination cluster is not allowed: unknown"},
+			{destination: cattagev1beta1.DestinationSpec{Name: "remote", Server: "https://kubernetes.default.svc"}, message: "destination cluster is not allowed: remote"},
+			{destination: cattagev1beta1.DestinationSpec{Name: "remote", Namespaces: []string{"["}}, message: "invalid namespace pattern"},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "o-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					ArgoCD: cattagev1beta1.ArgoCDSpec{
+						Destinations: []cattagev1beta1.DestinationSpec{tc.destination},
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should allow creating a tenant with allowed repositories", func() {
+		var warnings []string
+		cfg := rest.CopyConfig(testEnv.Config)
+		cfg.WarningHandler = warningRecorder(func(msg string) {
+			warnings = append(warnings, msg)
+		})
+		c, err := client.New(cfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "p-team",
+				Labels: map[string]string{"team": "p"},
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+					{Name: "app-p-team"},
+				},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Repositories: []string{
+						"https://github.com/cybozu-go/cattage.git",
+						"https://github.com/p-team/*",
+					},
+					RepositoryCredentials: []cattagev1beta1.RepositoryCredentialSpec{
+						{
+							URL:       "https://github.com/p-team/app.git",
+							SecretRef: cattagev1beta1.ObjectReference{Namespace: "app-p-team", Name: "repo-creds"},
+						},
+					},
+				},
+			},
+		}
+		err = c.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).Should(ContainElements(
+			"repository credentials are not generated because the template is not configured",
+			"secret app-p-team/repo-creds of the repository credential does not exist",
+		))
+	})
+
+	It("should deny creating a tenant with invalid repositories", func() {
+		testcases := []struct {
+			argocd  cattagev1beta1.ArgoCDSpec
+			message string
+		}{
+			{
+				argocd:  cattagev1beta1.ArgoCDSpec{Repositories: []string{"https://github.com/p-team/app.git"}},
+				message: "repository is not allowed: https://github.com/p-team/app.git",
+			},
+			{
+				argocd:  cattagev1beta1.ArgoCDSpec{Repositories: []string{"*"}},
+				message: "repository is not allowed: *",
+			},
+			{
+				argocd: cattagev1beta1.ArgoCDSpec{RepositoryCredentials: []cattagev1beta1.RepositoryCredentialSpec{
+					{URL: "https://example.com/app.git", SecretRef: cattagev1beta1.ObjectReference{Namespace: "app-q-team", Name: "repo-creds"}},
+				}},
+				message: "repository of the credential is not allowed: https://example.com/app.git",
+			},
+			{
+				argocd: cattagev1beta1.ArgoCDSpec{RepositoryCredentials: []cattagev1beta1.RepositoryCredentialSpec{
+					{URL: "https://github.com/cybozu-go/cattage.git", SecretRef: cattagev1beta1.ObjectReference{Namespace: "app-a-team", Name: "repo-creds"}},
+				}},
+				message: "secret of the repository credential https://github.com/cybozu-go/cattage.git must be in a namespace of the tenant",
+			},
+			{
+				argocd: cattagev1beta1.ArgoCDSpec{RepositoryCredentials: []cattagev1beta1.RepositoryCredentialSpec{
+					{URL: "https://github.com/cybozu-go/cattage.git", SecretRef: cattagev1beta1.ObjectReference{Namespace: "app-q-team", Name: "repo-creds"}},
+					{URL: "https://github.com/cybozu-go/cattage.git", SecretRef: cattagev1beta1.ObjectReference{Namespace: "app-q-team", Name: "other-creds"}},
+				}},
+				message: "duplicate repository credential: https://github.com/cybozu-go/cattage.git",
+			},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "q-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					RootNamespaces: []cattagev1beta1.RootNamespaceSpec{
+						{Name: "app-q-team"},
+					},
+					ArgoCD: tc.argocd,
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should deny creating a tenant with invalid project roles", func() {
+		policies := []cattagev1beta1.ProjectPolicySpec{
+			{Resources: []string{"applications"}, Verbs: []string{"get"}},
+		}
+		testcases := []struct {
+			roles   []cattagev1beta1.ProjectRoleSpec
+			message string
+		}{
+			{
+				roles: []cattagev1beta1.ProjectRoleSpec{
+					{Name: "delegate-a-team", Policies: policies},
+				},
+				message: "role name delegate-a-team is reserved for delegated tenants",
+			},
+			{
+				roles: []cattagev1beta1.ProjectRoleSpec{
+					{Name: "developer", Policies: policies},
+					{Name: "developer", Groups: []string{"cybozu-go:r-team"}},
+				},
+				message: "duplicate role: developer",
+			},
+			{
+				roles: []cattagev1beta1.ProjectRoleSpec{
+					{Name: "developer", Policies: []cattagev1beta1.ProjectPolicySpec{
+						{Resources: []string{"applications"}, Verbs: []string{"get, *"}},
+					}},
+				},
+				message: "spec.argocd.roles[0].policies[0].verbs[0]",
+			},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "r-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					ArgoCD: cattagev1beta1.ArgoCDSpec{
+						Roles: tc.roles,
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should allow creating a tenant with an allowed resource policy", func() {
+		var warnings []string
+		cfg := rest.CopyConfig(testEnv.Config)
+		cfg.WarningHandler = warningRecorder(func(msg string) {
+			warnings = append(warnings, msg)
+		})
+		c, err := client.New(cfg, client.Options{Scheme: k8sClient.Scheme()})
+		Expect(err).NotTo(HaveOccurred())
+
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "s-team",
+				Labels: map[string]string{"crd": "true"},
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					ResourcePolicy: &cattagev1beta1.ResourcePolicySpec{
+						Profile: "crd",
+						ClusterResourceWhitelist: []metav1.GroupKind{
+							{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"},
+							{Group: "", Kind: "Namespace"},
+						},
+						NamespaceResourceBlacklist: []metav1.GroupKind{
+							{Group: "", Kind: "Secret"},
+						},
+					},
+				},
+			},
+		}
+		err = c.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(warnings).Should(ConsistOf(
+			"/Namespace in clusterResourceWhitelist is ignored unless the template for AppProject allows it",
+		))
+	})
+
+	It("should deny creating a tenant with an invalid resource policy", func() {
+		testcases := []struct {
+			policy  cattagev1beta1.ResourcePolicySpec
+			message string
+		}{
+			{
+				policy:  cattagev1beta1.ResourcePolicySpec{Profile: "unknown"},
+				message: "resource policy unknown is not found",
+			},
+			{
+				policy:  cattagev1beta1.ResourcePolicySpec{Profile: "crd"},
+				message: "resource policy crd is not allowed for the tenant",
+			},
+			{
+				policy: cattagev1beta1.ResourcePolicySpec{
+					Profile:                    "restricted",
+					NamespaceResourceBlacklist: []metav1.GroupKind{{Group: "apps"}},
+				},
+				message: "kind is required in namespaceResourceBlacklist",
+			},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "t-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					ArgoCD: cattagev1beta1.ArgoCDSpec{
+						ResourcePolicy: &tc.policy,
+					},
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should allow creating a tenant with valid extra parameters", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "u-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				ExtraParams: &cattagev1beta1.Params{Data: map[string]interface{}{
+					"cpu":        2,
+					"GitHubTeam": "u-team-gh",
+				}},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should deny creating a tenant with invalid extra parameters", func() {
+		testcases := []struct {
+			params  map[string]interface{}
+			message string
+		}{
+			{
+				params:  map[string]interface{}{"cpu": "2"},
+				message: "extraParams.cpu in body must be of type integer",
+			},
+			{
+				params:  map[string]interface{}{"cpu": 0},
+				message: "extraParams.cpu in body should be greater than or equal to 1",
+			},
+			{
+				params:  map[string]interface{}{"env": "stage"},
+				message: "extraParams.env in body should be one of [dev prod]",
+			},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "v-team",
+				},
+				Spec: cattagev1beta1.TenantSpec{
+					ExtraParams: &cattagev1beta1.Params{Data: tc.params},
+				},
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should deny creating a tenant with invalid templates", func() {
+		testcases := []struct {
+			spec    cattagev1beta1.TenantSpec
+			message string
+		}{
+			{
+				spec:    cattagev1beta1.TenantSpec{ResourceQuotaTemplate: "spec:\n  hard: {{ .Name"},
+				message: "spec.resourceQuotaTemplate: Invalid value",
+			},
+			{
+				spec:    cattagev1beta1.TenantSpec{ResourceQuotaTemplate: "kind: LimitRange"},
+				message: "spec.resourceQuotaTemplate.kind: Invalid value",
+			},
+			{
+				spec:    cattagev1beta1.TenantSpec{LimitRangeTemplate: "spec:\n  limits: 1"},
+				message: "spec.limitRangeTemplate: Invalid value",
+			},
+		}
+		for _, tc := range testcases {
+			tenant := &cattagev1beta1.Tenant{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "x-team",
+				},
+				Spec: tc.spec,
+			}
+			err := k8sClient.Create(ctx, tenant)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).Should(ContainSubstring(tc.message))
+		}
+	})
+
+	It("should allow updating and deleting a tenant that is no longer valid for the configuration", func() {
+		tenant := &cattagev1beta1.Tenant{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "w-team",
+			},
+			Spec: cattagev1beta1.TenantSpec{
+				Delegates: []cattagev1beta1.DelegateSpec{
+					{
+						Name:  "a-team",
+						Roles: []string{"viewer"},
+					},
+				},
+				ControllerName: "second",
+				ExtraParams: &cattagev1beta1.Params{Data: map[string]interface{}{
+					"cpu": 2,
+				}},
+				ArgoCD: cattagev1beta1.ArgoCDSpec{
+					Destinations: []cattagev1beta1.DestinationSpec{
+						{Name: "remote"},
+					},
+					Repositories: []string{"https://github.com/cybozu-go/w-team"},
+					ResourcePolicy: &cattagev1beta1.ResourcePolicySpec{
+						Profile: "restricted",
+					},
+				},
+			},
+		}
+		err := k8sClient.Create(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+
+		orig := configHolder.Get()
+		newCfg := *orig
+		newCfg.ArgoCD.ApplicationControllers = []string{"third"}
+		newCfg.ArgoCD.Clusters = []config.ClusterConfig{{Name: "in-cluster", Server: "https://kubernetes.default.svc"}}
+		newCfg.ArgoCD.Repositories = config.RepositoriesConfig{AllowedPatterns: []string{"https://github.com/cybozu-go/cattage"}}
+		newCfg.ArgoCD.ResourcePolicies = nil
+		newCfg.Delegation.AllowedRoles = []string{"admin"}
+		schema := *orig.ExtraParams.Schema
+		schema.Required = []string{"owner"}
+		newCfg.ExtraParams.Schema = &schema
+		configHolder.Set(&newCfg)
+		defer configHolder.Set(orig)
+
+		By("updating an unrelated field")
+		tenant.Labels = map[string]string{"team": "w"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("adding a role that is not allowed")
+		tenant.Spec.Delegates[0].Roles = []string{"viewer", "editor"}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("role editor for delegate a-team is not allowed"))
+
+		By("adding a destination that is not allowed")
+		tenant.Spec.Delegates[0].Roles = []string{"viewer"}
+		tenant.Spec.ArgoCD.Destinations = append(tenant.Spec.ArgoCD.Destinations, cattagev1beta1.DestinationSpec{Server: "https://remote.example.com"})
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("destination cluster is not allowed: https://remote.example.com"))
+
+		By("adding a repository that is not allowed")
+		tenant.Spec.ArgoCD.Destinations = tenant.Spec.ArgoCD.Destinations[:1]
+		tenant.Spec.ArgoCD.Repositories = append(tenant.Spec.ArgoCD.Repositories, "https://github.com/cybozu-go/w-team2")
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("repository is not allowed: https://github.com/cybozu-go/w-team2"))
+
+		By("changing the extra parameters")
+		tenant.Spec.ArgoCD.Repositories = tenant.Spec.ArgoCD.Repositories[:1]
+		tenant.Spec.ExtraParams = &cattagev1beta1.Params{Data: map[string]interface{}{
+			"cpu": 3,
+		}}
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("owner in body is required"))
+
+		By("deleting the tenant")
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Delete(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(err).NotTo(HaveOccurred())
+		controllerutil.RemoveFinalizer(tenant, constants.Finalizer)
+		err = k8sClient.Update(ctx, tenant)
+		Expect(err).NotTo(HaveOccurred())
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(tenant), tenant)
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+	})
 })
+
+type warningRecorder func(string)
+
+func (f warningRecorder) HandleWarningHeader(code int, agent string, text string) {
+	f(text)
+}
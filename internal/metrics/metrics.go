@@ -8,6 +8,9 @@ import (
 const (
 	metricsNameSpace = "cattage"
 	tenantSubsystem  = "tenant"
+	configSubsystem  = "config"
+
+	syncWindowSubsystem = "syncwindow"
 )
 
 var (
@@ -24,8 +27,43 @@ var (
 		Name:      "unhealthy",
 		Help:      "The tenant status about unhealthy condition",
 	}, []string{"name"})
+
+	ControllerMigrationInProgressVec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNameSpace,
+		Subsystem: tenantSubsystem,
+		Name:      "controller_migration_in_progress",
+		Help:      "Whether the tenant is migrating between application controllers",
+	}, []string{"name"})
+
+	ControllerMigrationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNameSpace,
+		Subsystem: tenantSubsystem,
+		Name:      "controller_migrations_total",
+		Help:      "The number of completed migrations of tenants between application controllers",
+	}, []string{"from", "to"})
+
+	ConfigReloadFailuresTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNameSpace,
+		Subsystem: configSubsystem,
+		Name:      "reload_failures_total",
+		Help:      "The number of failures to reload the configuration file",
+	})
+
+	ConfigLastReloadSuccessful = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNameSpace,
+		Subsystem: configSubsystem,
+		Name:      "last_reload_successful",
+		Help:      "Whether the last reload of the configuration file succeeded",
+	})
+
+	SyncWindowActiveVec = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNameSpace,
+		Subsystem: syncWindowSubsystem,
+		Name:      "active",
+		Help:      "The number of active sync windows in the SyncWindow resource",
+	}, []string{"tenant", "namespace", "name", "kind"})
 )
 
 func init() {
-	k8smetrics.Registry.MustRegister(HealthyVec, UnhealthyVec)
+	k8smetrics.Registry.MustRegister(HealthyVec, UnhealthyVec, ControllerMigrationInProgressVec, ControllerMigrationsTotal, ConfigReloadFailuresTotal, ConfigLastReloadSuccessful, SyncWindowActiveVec)
 }
@@ -0,0 +1,53 @@
+package syncwindow
+
+import (
+	"time"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+)
+
+// maxOverlaps is the maximum number of overlapping windows followed to find the end of an active window.
+// A window that keeps opening before it closes is regarded as active until the last followed one closes.
+const maxOverlaps = 1000
+
+// State returns whether the sync window is active at now and the time when it opens or closes next.
+// The returned time is zero if the sync window never opens.
+// The sync window must be valid.
+func State(w *cattagev1beta1.SyncWindowSetting, now time.Time) (active bool, next time.Time, err error) {
+	schedule, err := scheduleParser.Parse(w.Schedule)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	duration, err := time.ParseDuration(w.Duration)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	loc := time.UTC
+	if w.TimeZone != "" {
+		loc, err = time.LoadLocation(w.TimeZone)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+	}
+	now = now.In(loc)
+
+	// The window is active if it opened within the duration, in the same way as Argo CD.
+	start := schedule.Next(now.Add(-duration))
+	if start.IsZero() {
+		// The schedule never matches.
+		return false, time.Time{}, nil
+	}
+	if start.After(now) {
+		return false, start.UTC(), nil
+	}
+
+	end := start.Add(duration)
+	for range maxOverlaps {
+		start = schedule.Next(start)
+		if start.IsZero() || start.After(end) {
+			break
+		}
+		end = start.Add(duration)
+	}
+	return true, end.UTC(), nil
+}
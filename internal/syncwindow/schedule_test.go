@@ -0,0 +1,68 @@
+package syncwindow
+
+import (
+	"testing"
+	"time"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+)
+
+func TestState(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name    string
+		setting *cattagev1beta1.SyncWindowSetting
+		active  bool
+		next    time.Time
+	}{
+		{
+			name:    "active",
+			setting: &cattagev1beta1.SyncWindowSetting{Schedule: "0 12 * * *", Duration: "1h"},
+			active:  true,
+			next:    time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "inactive",
+			setting: &cattagev1beta1.SyncWindowSetting{Schedule: "0 22 * * *", Duration: "1h"},
+			active:  false,
+			next:    time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "closed just now",
+			setting: &cattagev1beta1.SyncWindowSetting{Schedule: "0 12 * * *", Duration: "30m"},
+			active:  false,
+			next:    time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "time zone",
+			setting: &cattagev1beta1.SyncWindowSetting{Schedule: "0 21 * * *", Duration: "1h", TimeZone: "Asia/Tokyo"},
+			active:  true,
+			next:    time.Date(2024, 1, 1, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "overlapping windows",
+			setting: &cattagev1beta1.SyncWindowSetting{Schedule: "0 * * * *", Duration: "90m"},
+			active:  true,
+			next:    time.Date(2024, 1, 1, 13, 30, 0, 0, time.UTC).Add(maxOverlaps * time.Hour),
+		},
+		{
+			name:    "never",
+			setting: &cattagev1beta1.SyncWindowSetting{Schedule: "0 0 30 2 *", Duration: "1h"},
+			active:  false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		active, next, err := State(testcase.setting, now)
+		if err != nil {
+			t.Fatalf("%s: %s", testcase.name, err)
+		}
+		if active != testcase.active {
+			t.Errorf("%s: expected active %v, actual %v", testcase.name, testcase.active, active)
+		}
+		if !next.Equal(testcase.next) {
+			t.Errorf("%s: expected next %s, actual %s", testcase.name, testcase.next, next)
+		}
+	}
+}
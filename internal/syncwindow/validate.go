@@ -0,0 +1,79 @@
+package syncwindow
+
+import (
+	"time"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	"github.com/robfig/cron/v3"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// scheduleParser parses schedules of sync windows in the same way as Argo CD.
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Validate validates the sync windows in the SyncWindow resource.
+// Only the fields that Argo CD checks are validated. The namespaces are not checked.
+func Validate(sw *cattagev1beta1.SyncWindow) field.ErrorList {
+	return validateSettings(field.NewPath("spec", "syncWindows"), sw.Spec.SyncWindows)
+}
+
+// ValidateCluster validates the sync windows and the tenant selector in the ClusterSyncWindow resource.
+func ValidateCluster(csw *cattagev1beta1.ClusterSyncWindow) field.ErrorList {
+	p := field.NewPath("spec")
+	allErrs := validateSettings(p.Child("syncWindows"), csw.Spec.SyncWindows)
+	if csw.Spec.TenantSelector == nil && len(csw.Spec.Tenants) == 0 {
+		allErrs = append(allErrs, field.Required(p, "one of tenantSelector or tenants is required"))
+	}
+	if csw.Spec.TenantSelector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(csw.Spec.TenantSelector, metav1validation.LabelSelectorValidationOptions{}, p.Child("tenantSelector"))...)
+	}
+	for i, name := range csw.Spec.Tenants {
+		for _, msg := range validation.IsDNS1123Subdomain(name) {
+			allErrs = append(allErrs, field.Invalid(p.Child("tenants").Index(i), name, msg))
+		}
+	}
+	return allErrs
+}
+
+func validateSettings(p *field.Path, windows cattagev1beta1.SyncWindows) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, w := range windows {
+		allErrs = append(allErrs, ValidateSetting(p.Index(i), w)...)
+	}
+	return allErrs
+}
+
+// ValidateSetting validates a sync window.
+func ValidateSetting(p *field.Path, w *cattagev1beta1.SyncWindowSetting) field.ErrorList {
+	var allErrs field.ErrorList
+	if w == nil {
+		return append(allErrs, field.Required(p, "sync window should not be null"))
+	}
+
+	if w.Kind != "allow" && w.Kind != "deny" {
+		allErrs = append(allErrs, field.NotSupported(p.Child("kind"), w.Kind, []string{"allow", "deny"}))
+	}
+	if w.Schedule == "" {
+		allErrs = append(allErrs, field.Required(p.Child("schedule"), ""))
+	} else if _, err := scheduleParser.Parse(w.Schedule); err != nil {
+		allErrs = append(allErrs, field.Invalid(p.Child("schedule"), w.Schedule, err.Error()))
+	}
+	if w.Duration == "" {
+		allErrs = append(allErrs, field.Required(p.Child("duration"), ""))
+	} else if d, err := time.ParseDuration(w.Duration); err != nil {
+		allErrs = append(allErrs, field.Invalid(p.Child("duration"), w.Duration, err.Error()))
+	} else if d <= 0 {
+		allErrs = append(allErrs, field.Invalid(p.Child("duration"), w.Duration, "should be positive"))
+	}
+	if w.TimeZone != "" {
+		if _, err := time.LoadLocation(w.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(p.Child("timeZone"), w.TimeZone, err.Error()))
+		}
+	}
+	if len(w.Applications) == 0 && len(w.Namespaces) == 0 && len(w.Clusters) == 0 {
+		allErrs = append(allErrs, field.Required(p, "one of applications, namespaces or clusters is required"))
+	}
+	return allErrs
+}
@@ -0,0 +1,207 @@
+package syncwindow
+
+import (
+	"testing"
+
+	cattagev1beta1 "github.com/cybozu-go/cattage/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidate(t *testing.T) {
+	testcases := []struct {
+		name    string
+		setting *cattagev1beta1.SyncWindowSetting
+		isValid bool
+	}{
+		{
+			name: "valid sync window",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "allow",
+				Schedule:   "0 22 * * *",
+				Duration:   "1h30m",
+				TimeZone:   "Asia/Tokyo",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: true,
+		},
+		{
+			name: "descriptor schedule",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:         "deny",
+				Schedule:     "@daily",
+				Duration:     "1h",
+				Applications: []string{"*"},
+			},
+			isValid: true,
+		},
+		{
+			name:    "null",
+			setting: nil,
+			isValid: false,
+		},
+		{
+			name: "unknown kind",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "block",
+				Schedule:   "0 22 * * *",
+				Duration:   "1h",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid schedule",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "allow",
+				Schedule:   "0 25 * * *",
+				Duration:   "1h",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: false,
+		},
+		{
+			name: "schedule with seconds",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "allow",
+				Schedule:   "0 0 22 * * *",
+				Duration:   "1h",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid duration",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "allow",
+				Schedule:   "0 22 * * *",
+				Duration:   "1 hour",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: false,
+		},
+		{
+			name: "negative duration",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "allow",
+				Schedule:   "0 22 * * *",
+				Duration:   "-1h",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: false,
+		},
+		{
+			name: "unknown time zone",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:       "allow",
+				Schedule:   "0 22 * * *",
+				Duration:   "1h",
+				TimeZone:   "Mars/Olympus",
+				Namespaces: []string{"sub-1"},
+			},
+			isValid: false,
+		},
+		{
+			name: "no targets",
+			setting: &cattagev1beta1.SyncWindowSetting{
+				Kind:     "allow",
+				Schedule: "0 22 * * *",
+				Duration: "1h",
+			},
+			isValid: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		sw := &cattagev1beta1.SyncWindow{
+			Spec: cattagev1beta1.SyncWindowSpec{
+				SyncWindows: cattagev1beta1.SyncWindows{testcase.setting},
+			},
+		}
+		errs := Validate(sw)
+		if testcase.isValid && len(errs) != 0 {
+			t.Fatalf("%s: %s", testcase.name, errs.ToAggregate())
+		}
+		if !testcase.isValid && len(errs) == 0 {
+			t.Fatalf("%s: invalid data are validated successfully", testcase.name)
+		}
+	}
+}
+
+func TestValidateCluster(t *testing.T) {
+	window := &cattagev1beta1.SyncWindowSetting{
+		Kind:         "deny",
+		Schedule:     "0 0 24 12 *",
+		Duration:     "72h",
+		Applications: []string{"*"},
+	}
+	testcases := []struct {
+		name    string
+		spec    cattagev1beta1.ClusterSyncWindowSpec
+		isValid bool
+	}{
+		{
+			name: "empty selector",
+			spec: cattagev1beta1.ClusterSyncWindowSpec{
+				TenantSelector: &metav1.LabelSelector{},
+				SyncWindows:    cattagev1beta1.SyncWindows{window},
+			},
+			isValid: true,
+		},
+		{
+			name: "tenant names",
+			spec: cattagev1beta1.ClusterSyncWindowSpec{
+				Tenants:     []string{"a-team", "b-team"},
+				SyncWindows: cattagev1beta1.SyncWindows{window},
+			},
+			isValid: true,
+		},
+		{
+			name: "no tenants",
+			spec: cattagev1beta1.ClusterSyncWindowSpec{
+				SyncWindows: cattagev1beta1.SyncWindows{window},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid selector",
+			spec: cattagev1beta1.ClusterSyncWindowSpec{
+				TenantSelector: &metav1.LabelSelector{
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{Key: "tier", Operator: metav1.LabelSelectorOpIn},
+					},
+				},
+				SyncWindows: cattagev1beta1.SyncWindows{window},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid tenant name",
+			spec: cattagev1beta1.ClusterSyncWindowSpec{
+				Tenants:     []string{"A_team"},
+				SyncWindows: cattagev1beta1.SyncWindows{window},
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid sync window",
+			spec: cattagev1beta1.ClusterSyncWindowSpec{
+				Tenants: []string{"a-team"},
+				SyncWindows: cattagev1beta1.SyncWindows{
+					{Kind: "allow", Schedule: "0 25 * * *", Duration: "1h", Applications: []string{"*"}},
+				},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, testcase := range testcases {
+		csw := &cattagev1beta1.ClusterSyncWindow{Spec: testcase.spec}
+		errs := ValidateCluster(csw)
+		if testcase.isValid && len(errs) != 0 {
+			t.Fatalf("%s: %s", testcase.name, errs.ToAggregate())
+		}
+		if !testcase.isValid && len(errs) == 0 {
+			t.Fatalf("%s: invalid data are validated successfully", testcase.name)
+		}
+	}
+}